@@ -0,0 +1,311 @@
+// admin.go
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stateManifest is manifest.json in a state archive.
+type stateManifest struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Host     string    `json:"host,omitempty"`
+	Defaults bool      `json:"defaults"`
+	History  int       `json:"history"`
+	Profiles []string  `json:"profiles,omitempty"`
+}
+
+// stateArchiveVersion is the layout version of state archives.
+const stateArchiveVersion = 1
+
+func runAdmin(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: codexgigantus admin export|import [flags]")
+	}
+
+	switch args[0] {
+	case "export":
+		return runAdminExport(args[1:])
+	case "import":
+		return runAdminImport(args[1:])
+	default:
+		return fmt.Errorf("unknown admin command: %s", args[0])
+	}
+}
+
+// runAdminExport bundles the per-user defaults, the run history and the
+// profiles named on the command line into a .tar.gz archive.
+func runAdminExport(args []string) error {
+	fs := flag.NewFlagSet("admin export", flag.ExitOnError)
+	outFlag := fs.String("out", "codexgigantus-state.tar.gz", "Archive to write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	tw := tar.NewWriter(gz)
+	manifest := stateManifest{Version: stateArchiveVersion, Created: time.Now().UTC()}
+	manifest.Host, _ = os.Hostname()
+
+	add := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if path := defaultsPath(); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err == nil {
+			if err := add("defaults.json", data); err != nil {
+				return err
+			}
+			manifest.Defaults = true
+		}
+	}
+
+	entries, err := loadHistory(historyPath())
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	if len(entries) > 0 {
+		var history bytes.Buffer
+		for _, entry := range entries {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			history.Write(append(line, '\n'))
+		}
+		if err := add("history.jsonl", history.Bytes()); err != nil {
+			return err
+		}
+		manifest.History = len(entries)
+	}
+
+	seen := make(map[string]bool)
+	for _, profile := range fs.Args() {
+		name := filepath.Base(profile)
+		if seen[name] {
+			return fmt.Errorf("two profiles named %s", name)
+		}
+		seen[name] = true
+		data, err := os.ReadFile(profile)
+		if err != nil {
+			return err
+		}
+		if err := add("profiles/"+name, data); err != nil {
+			return err
+		}
+		manifest.Profiles = append(manifest.Profiles, name)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := add("manifest.json", append(data, '\n')); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := SaveOutput(buffer.String(), *outFlag); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported defaults: %v, %d history entries, %d profiles to %s\n", manifest.Defaults, manifest.History, len(manifest.Profiles), *outFlag)
+	return nil
+}
+
+// runAdminImport restores an archive written by admin export. History
+// entries are merged with the local history; defaults and profiles are
+// only overwritten with -force.
+func runAdminImport(args []string) error {
+	fs := flag.NewFlagSet("admin import", flag.ExitOnError)
+	profilesFlag := fs.String("profiles", ".", "Directory the profiles are written to")
+	forceFlag := fs.Bool("force", false, "Overwrite existing defaults and profiles")
+	dryRunFlag := fs.Bool("dry-run", false, "List what would be imported")
+
+	// Accept the archive before or after the flags.
+	var archive string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		archive, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if archive == "" {
+		archive = fs.Arg(0)
+	}
+	if archive == "" {
+		return errors.New("usage: codexgigantus admin import <archive> [-profiles dir] [-force]")
+	}
+
+	files, err := readStateArchive(archive)
+	if err != nil {
+		return err
+	}
+	var manifest stateManifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		return fmt.Errorf("%s: missing or invalid manifest.json", archive)
+	}
+	if manifest.Version > stateArchiveVersion {
+		return fmt.Errorf("%s was written by a newer release (archive version %d); please upgrade", archive, manifest.Version)
+	}
+
+	// Check everything before writing anything.
+	type write struct {
+		path string
+		data []byte
+	}
+	var writes []write
+	if data, ok := files["defaults.json"]; ok {
+		if err := checkConfigVersion(data); err != nil {
+			return fmt.Errorf("defaults.json: %w", err)
+		}
+		path := defaultsPath()
+		if path == "" {
+			return errors.New("per-user defaults are disabled; set CODEXGIGANTUS_DEFAULTS to import them")
+		}
+		if err := checkOverwrite(path, data, *forceFlag); err != nil {
+			return err
+		}
+		writes = append(writes, write{path, data})
+	}
+	for name, data := range files {
+		if !strings.HasPrefix(name, "profiles/") {
+			continue
+		}
+		path := filepath.Join(*profilesFlag, path.Base(name))
+		if err := checkOverwrite(path, data, *forceFlag); err != nil {
+			return err
+		}
+		writes = append(writes, write{path, data})
+	}
+
+	var merged []HistoryEntry
+	if data, ok := files["history.jsonl"]; ok {
+		local, err := loadHistory(historyPath())
+		if err != nil {
+			return fmt.Errorf("reading history: %w", err)
+		}
+		known := make(map[string]bool)
+		for _, entry := range local {
+			known[entry.RunID+entry.Time.String()] = true
+		}
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			var entry HistoryEntry
+			if json.Unmarshal(line, &entry) == nil && !known[entry.RunID+entry.Time.String()] {
+				merged = append(merged, entry)
+			}
+		}
+	}
+
+	for _, w := range writes {
+		if *dryRunFlag {
+			fmt.Printf("Would write %s\n", w.path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+			return err
+		}
+		if err := SaveOutput(string(w.data), w.path); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", w.path)
+	}
+	if *dryRunFlag {
+		fmt.Printf("Would add %d history entries to %s\n", len(merged), historyPath())
+		return nil
+	}
+	for _, entry := range merged {
+		if err := appendHistory(historyPath(), entry); err != nil {
+			return fmt.Errorf("writing history: %w", err)
+		}
+	}
+	fmt.Printf("Added %d history entries to %s\n", len(merged), historyPath())
+	return nil
+}
+
+// checkOverwrite refuses to replace an existing, different file unless
+// force is set. A file that cannot be read is reported, not overwritten.
+func checkOverwrite(path string, data []byte, force bool) error {
+	if force {
+		return nil
+	}
+	existing, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(existing, data) {
+		return nil
+	}
+	return fmt.Errorf("%s already exists; use -force to overwrite it", path)
+}
+
+// readStateArchive returns the regular files of a state archive by name.
+// Only the names admin export writes are accepted.
+func readStateArchive(archive string) (map[string][]byte, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", archive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", archive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		switch {
+		case name == "manifest.json" || name == "defaults.json" || name == "history.jsonl":
+		case path.Dir(name) == "profiles" && !strings.HasPrefix(path.Base(name), "."):
+		default:
+			return nil, fmt.Errorf("%s: unexpected entry %s", archive, header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
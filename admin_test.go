@@ -0,0 +1,89 @@
+// admin_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "profile.json")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unreadable := filepath.Join(dir, "directory.json")
+	if err := os.Mkdir(unreadable, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		path  string
+		data  string
+		force bool
+		want  string
+	}{
+		{name: "missing", path: filepath.Join(dir, "new.json"), data: "new"},
+		{name: "same content", path: existing, data: "old"},
+		{name: "different", path: existing, data: "new", want: "already exists"},
+		{name: "different with force", path: existing, data: "new", force: true},
+		{name: "unreadable", path: unreadable, data: "new", want: "is a directory"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkOverwrite(tc.path, []byte(tc.data), tc.force)
+			if tc.want == "" && err != nil {
+				t.Errorf("got %v, want no error", err)
+			}
+			if tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+				t.Errorf("got %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestAdminImportRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CODEXGIGANTUS_DEFAULTS", filepath.Join(dir, "defaults.json"))
+	t.Setenv("CODEXGIGANTUS_HISTORY", filepath.Join(dir, "history.jsonl"))
+	profile := filepath.Join(dir, "work.json")
+	if err := os.WriteFile(profile, []byte(`{"format": "json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "state.tar.gz")
+	if err := runAdmin([]string{"export", "-out", archive, profile}); err != nil {
+		t.Fatal(err)
+	}
+
+	into := t.TempDir()
+	target := filepath.Join(into, "work.json")
+	if err := os.WriteFile(target, []byte(`{"format": "aider"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAdmin([]string{"import", archive, "-profiles", into}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("import over a different profile returned %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != `{"format": "aider"}` {
+		t.Errorf("refused import still wrote %s", data)
+	}
+
+	if err := runAdmin([]string{"import", archive, "-profiles", into, "-force"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != `{"format": "json"}` {
+		t.Errorf("-force wrote %s", data)
+	}
+
+	if err := os.Remove(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAdmin([]string{"import", archive, "-profiles", into}); err == nil {
+		t.Error("import over an unreadable profile succeeded")
+	}
+}
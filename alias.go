@@ -0,0 +1,80 @@
+// alias.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// expandAlias replaces a leading alias name with the arguments it stands
+// for. Aliases are read from the per-user defaults and the config file
+// given with -config, and may start with a command, e.g. "nightly" = "batch -parallel 4 nightly.json".
+// Aliases are expanded once, so they cannot refer to other aliases, and
+// command names always win over an alias of the same name.
+func expandAlias(args []string) ([]string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return args, nil
+	}
+	if _, ok := commands[args[0]]; ok {
+		return args, nil
+	}
+
+	config, err := userDefaults()
+	if path := flagValueFromArgs(args[1:], "config"); path != "" {
+		config, err = LoadConfigFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, ok := config.Aliases[args[0]]
+	if !ok {
+		return args, nil
+	}
+
+	expanded, err := splitArgs(value)
+	if err != nil {
+		return nil, fmt.Errorf("alias %s: %w", args[0], err)
+	}
+	return append(expanded, args[1:]...), nil
+}
+
+// splitArgs splits s on whitespace like a shell, honouring single and
+// double quotes and backslash escapes outside single quotes.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg, quote, escaped := false, rune(0), false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,18 @@
+// banner.go
+package main
+
+import "strings"
+
+const defaultBannerText = `Generated by codexgigantus at {time} on {host} (run {run_id})
+Files: {files}, estimated tokens: {tokens}
+WARNING: this dump may contain secrets or credentials. Review it before sharing.`
+
+func renderBanner(text string, results []FileResult, body string, config *Config) string {
+	vars := templateVars(config, len(results), estimateTokens(body, config.Tokenizer))
+
+	var lines []string
+	for _, line := range strings.Split(expandTemplate(text, vars), "\n") {
+		lines = append(lines, strings.TrimRight("# "+line, " "))
+	}
+	return strings.Join(lines, "\n") + "\n\n"
+}
@@ -0,0 +1,235 @@
+// batch.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BatchManifest lists the repositories or directories processed by the
+// batch command. Each entry may point to its own profile.
+type BatchManifest struct {
+	OutputDir string       `json:"output_dir"`
+	Entries   []BatchEntry `json:"entries"`
+}
+
+type BatchEntry struct {
+	Name       string   `json:"name"`
+	Dirs       []string `json:"dirs"`
+	Profile    string   `json:"profile,omitempty"`
+	OutputFile string   `json:"output_file,omitempty"`
+	Source     string   `json:"source,omitempty"`
+}
+
+type batchOutcome struct {
+	Entry    BatchEntry
+	Output   string
+	Files    int
+	Bytes    int
+	Tokens   int
+	Duration time.Duration
+	// Unchanged is set when the output file already held this output.
+	Unchanged bool
+	Warnings  []Warning
+	Err       error
+	// Result is the entry's RunResult, written to summary.json.
+	Result *RunResult
+}
+
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	parallelFlag := fs.Int("parallel", 1, "Number of entries processed concurrently")
+	outDirFlag := fs.String("out-dir", "", "Directory for the per-entry outputs (overrides the manifest's output_dir)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: codexgigantus batch [-parallel N] [-out-dir dir] manifest.json")
+	}
+
+	manifest, err := loadBatchManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	outDir := manifest.OutputDir
+	if *outDirFlag != "" {
+		outDir = *outDirFlag
+	}
+	if outDir == "" {
+		outDir = "."
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	runID := newRunID()
+	outcomes := make([]batchOutcome, len(manifest.Entries))
+	workers := *parallelFlag
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, entry := range manifest.Entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, entry BatchEntry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			outcomes[i] = processBatchEntry(entry, outDir, runID)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	summary := formatBatchSummary(runID, outcomes)
+	fmt.Print(summary)
+	if err := SaveOutput(summary, filepath.Join(outDir, "summary.txt")); err != nil {
+		return err
+	}
+
+	results := make([]*RunResult, len(outcomes))
+	for i, outcome := range outcomes {
+		results[i] = outcome.Result
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := SaveOutput(string(data)+"\n", filepath.Join(outDir, "summary.json")); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries failed", failed, len(outcomes))
+	}
+	return nil
+}
+
+func loadBatchManifest(path string) (*BatchManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BatchManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	for i, entry := range manifest.Entries {
+		if entry.Name == "" || len(entry.Dirs) == 0 {
+			return nil, fmt.Errorf("manifest entry %d needs a name and at least one dir", i+1)
+		}
+	}
+	return manifest, nil
+}
+
+func processBatchEntry(entry BatchEntry, outDir, runID string) (outcome batchOutcome) {
+	start := time.Now()
+	outcome.Entry = entry
+	defer func() {
+		if outcome.Result == nil {
+			// The entry failed before it had a config.
+			outcome.Result = newRunResult(nil, &Config{RunID: runID, started: start}, outcome.Err)
+			outcome.Result.Name = entry.Name
+		}
+	}()
+
+	config, err := userDefaults()
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	if entry.Profile != "" {
+		loaded, err := LoadConfigFile(entry.Profile)
+		if err != nil {
+			outcome.Err = err
+			return outcome
+		}
+		config = loaded
+	}
+	config.RunID = runID
+	config.started = start
+	config.Dirs = entry.Dirs
+	config.Source = entry.Source
+	config.Save = true
+	config.OutputFile = entry.OutputFile
+	if config.OutputFile == "" {
+		config.OutputFile = safeFileName(entry.Name + ".txt")
+	}
+
+	output, results, included, err := generate(config)
+	config.OutputFile = filepath.Join(outDir, expandTemplate(config.OutputFile, templateVars(config, len(results), totalTokens(results))))
+	if err == nil {
+		var changed bool
+		changed, err = saveIfChanged(output, config.OutputFile, config)
+		outcome.Unchanged = !changed
+	}
+	if err == nil && config.Delta {
+		recordManifest(included, config)
+	}
+
+	outcome.Output = config.OutputFile
+	outcome.Files = len(results)
+	outcome.Bytes = len(output)
+	outcome.Tokens = estimateTokens(output, config.Tokenizer)
+	outcome.Duration = time.Since(start)
+	if err == nil {
+		recordHistory(results, start, config)
+	}
+	outcome.Warnings = config.Warnings.List()
+	outcome.Err = err
+
+	outcome.Result = newRunResult(results, config, err)
+	outcome.Result.Name = entry.Name
+	if err == nil {
+		outcome.Result.Artifacts = []string{config.OutputFile}
+		if outcome.Unchanged {
+			outcome.Result.Status = "unchanged"
+		}
+	}
+	return outcome
+}
+
+func formatBatchSummary(runID string, outcomes []batchOutcome) string {
+	var buffer bytes.Buffer
+	totalFiles, totalBytes, totalTokens := 0, 0, 0
+
+	buffer.WriteString(fmt.Sprintf("Run: %s\n", runID))
+
+	buffer.WriteString(fmt.Sprintf("%-24s %-9s %8s %12s %10s %10s  %s\n", "ENTRY", "STATUS", "FILES", "SIZE", "TOKENS", "DURATION", "OUTPUT"))
+	for _, outcome := range outcomes {
+		status := "ok"
+		if outcome.Err != nil {
+			status = "failed"
+		} else if outcome.Unchanged {
+			status = "unchanged"
+		}
+		buffer.WriteString(fmt.Sprintf("%-24s %-9s %8d %12s %10d %10s  %s\n", outcome.Entry.Name, status, outcome.Files, formatSize(int64(outcome.Bytes)), outcome.Tokens, formatDuration(outcome.Duration), outcome.Output))
+		if outcome.Err != nil {
+			buffer.WriteString(fmt.Sprintf("  error: %v\n", outcome.Err))
+		}
+		for _, warning := range outcome.Warnings {
+			buffer.WriteString(fmt.Sprintf("  warning: %s %s: %s\n", warning.Kind, warning.Path, warning.Message))
+		}
+		totalFiles += outcome.Files
+		totalBytes += outcome.Bytes
+		totalTokens += outcome.Tokens
+	}
+	buffer.WriteString(fmt.Sprintf("%-24s %-9s %8d %12s %10d\n", "TOTAL", "", totalFiles, formatSize(int64(totalBytes)), totalTokens))
+
+	return buffer.String()
+}
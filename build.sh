@@ -2,7 +2,7 @@
 
 # Build the Go project
 
-go build -o codexgigantus main.go config.go file_processor.go utils.go
+go build -o codexgigantus .
 
 # Make the binary executable
 chmod +x codexgigantus
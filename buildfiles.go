@@ -0,0 +1,210 @@
+// buildfiles.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	makeTargetRe   = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_./-]*)\s*:([^=]|$)`)
+	justRecipeRe   = regexp.MustCompile(`^@?([A-Za-z0-9][A-Za-z0-9_-]*)(\s+[^:=]*)?:([^=]|$)`)
+	dockerStepRe   = regexp.MustCompile(`(?i)^(FROM|EXPOSE|ENTRYPOINT|CMD)\s+(.+)`)
+	yamlRunRe      = regexp.MustCompile(`^\s*(?:-\s*)?(?:run|script|cmd):\s*(.+)`)
+	yamlListItemRe = regexp.MustCompile(`^\s*-\s+(.+)`)
+	yamlKeyRe      = regexp.MustCompile(`^(\s*)([A-Za-z0-9_.-]+):\s*(.*)$`)
+	jenkinsStageRe = regexp.MustCompile(`stage\s*\(\s*['"]([^'"]+)['"]`)
+)
+
+// buildFileKind names the kind of build or automation file at path, or ""
+// for other files.
+func buildFileKind(p string) string {
+	slashed := filepath.ToSlash(p)
+	base := path.Base(slashed)
+	lower := strings.ToLower(base)
+	switch {
+	case base == "Makefile" || base == "GNUmakefile" || lower == "makefile" || strings.HasSuffix(lower, ".mk"):
+		return "make"
+	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") || strings.HasSuffix(base, ".Dockerfile"):
+		return "docker"
+	case strings.Contains(slashed, ".github/workflows/") && (strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml")):
+		return "github-actions"
+	case lower == "taskfile.yml" || lower == "taskfile.yaml":
+		return "task"
+	case lower == "justfile" || lower == ".justfile":
+		return "just"
+	case lower == ".gitlab-ci.yml":
+		return "gitlab-ci"
+	case base == "Jenkinsfile":
+		return "jenkins"
+	case lower == "docker-compose.yml" || lower == "docker-compose.yaml" || lower == "compose.yml" || lower == "compose.yaml":
+		return "compose"
+	}
+	return ""
+}
+
+// prioritizeBuildFiles moves build and automation files to the front,
+// keeping the order within both groups.
+func prioritizeBuildFiles(results []FileResult) []FileResult {
+	sorted := append([]FileResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return buildFileKind(sorted[i].Path) != "" && buildFileKind(sorted[j].Path) == ""
+	})
+	return sorted
+}
+
+// buildSection summarises how the project builds: the make and just
+// targets, Dockerfile stages, CI jobs and the commands they run. The files
+// themselves stay in the output.
+func buildSection(results []FileResult) (Section, bool) {
+	var buffer strings.Builder
+	for _, result := range results {
+		kind := buildFileKind(result.Path)
+		if kind == "" {
+			continue
+		}
+		lines := extractBuildSteps(kind, result.Content)
+		buffer.WriteString(fmt.Sprintf("%s (%s)\n", filepath.ToSlash(result.Path), kind))
+		for _, line := range lines {
+			buffer.WriteString("  " + line + "\n")
+		}
+	}
+	if buffer.Len() == 0 {
+		return Section{}, false
+	}
+	return Section{Title: "How this project builds", Content: buffer.String()}, true
+}
+
+func extractBuildSteps(kind, content string) []string {
+	var steps []string
+	lines := strings.Split(content, "\n")
+	yamlSection := ""
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		switch kind {
+		case "make":
+			if m := makeTargetRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(m[1], ".") {
+				steps = append(steps, "target "+m[1]+firstRecipe(lines[i+1:]))
+			}
+		case "just":
+			if m := justRecipeRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+				steps = append(steps, "recipe "+m[1]+firstRecipe(lines[i+1:]))
+			}
+		case "docker":
+			if m := dockerStepRe.FindStringSubmatch(trimmed); m != nil {
+				steps = append(steps, strings.ToUpper(m[1])+" "+m[2])
+			}
+		case "jenkins":
+			if m := jenkinsStageRe.FindStringSubmatch(line); m != nil {
+				steps = append(steps, "stage "+m[1])
+			}
+		default:
+			m := yamlKeyRe.FindStringSubmatch(line)
+			if m != nil && m[1] == "" {
+				yamlSection = m[2]
+			}
+			// Hidden GitLab jobs (.name) are templates that never run.
+			if kind == "gitlab-ci" && strings.HasPrefix(yamlSection, ".") {
+				continue
+			}
+			switch {
+			case m != nil && kind == "github-actions" && m[1] == "" && m[2] == "name":
+				steps = append(steps, "workflow "+strings.Trim(m[3], `"'`))
+			case m != nil && m[1] == "" && m[2] == "on":
+				if m[3] != "" {
+					steps = append(steps, "on "+m[3])
+				}
+			case m != nil && len(m[1]) == 2 && isYAMLGroup(kind, yamlSection):
+				steps = append(steps, yamlGroupLabel(kind)+" "+m[2])
+			case m != nil && m[1] == "" && kind == "gitlab-ci" && !strings.HasPrefix(m[2], ".") && !gitlabKeywords[m[2]]:
+				steps = append(steps, "job "+m[2])
+			default:
+				if run := yamlRunRe.FindStringSubmatch(line); run != nil && run[1] != "|" && run[1] != ">" {
+					steps = append(steps, "  run: "+strings.Trim(run[1], `"'`))
+				} else if run != nil {
+					steps = append(steps, "  run: "+blockScalar(lines[i+1:]))
+				} else if kind != "compose" && yamlSection != "" && yamlListItemRe.MatchString(line) && isCommandList(lines, i) {
+					item := strings.Trim(yamlListItemRe.FindStringSubmatch(line)[1], `"'`)
+					// A Taskfile cmd can call another task.
+					if task, ok := strings.CutPrefix(item, "task: "); ok && kind == "task" {
+						item = "task " + task
+					}
+					steps = append(steps, "  run: "+item)
+				}
+			}
+		}
+	}
+	return steps
+}
+
+var gitlabKeywords = map[string]bool{
+	"stages": true, "variables": true, "default": true, "include": true, "workflow": true,
+	"image": true, "services": true, "before_script": true, "after_script": true, "cache": true,
+}
+
+func isYAMLGroup(kind, section string) bool {
+	switch kind {
+	case "github-actions":
+		return section == "jobs"
+	case "task":
+		return section == "tasks"
+	case "compose":
+		return section == "services"
+	}
+	return false
+}
+
+func yamlGroupLabel(kind string) string {
+	switch kind {
+	case "task":
+		return "task"
+	case "compose":
+		return "service"
+	}
+	return "job"
+}
+
+// isCommandList reports whether the list item at lines[i] belongs to a
+// script, cmds or commands key.
+func isCommandList(lines []string, i int) bool {
+	indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+	for j := i - 1; j >= 0; j-- {
+		m := yamlKeyRe.FindStringSubmatch(lines[j])
+		if m == nil {
+			continue
+		}
+		if len(m[1]) < indent || (len(m[1]) == indent && !strings.HasPrefix(strings.TrimSpace(lines[j]), "-")) {
+			return m[3] == "" && (m[2] == "script" || m[2] == "cmds" || m[2] == "commands")
+		}
+	}
+	return false
+}
+
+// firstRecipe returns the first command of a make or just recipe.
+func firstRecipe(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	if line := lines[0]; strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
+		return ": " + strings.TrimLeft(strings.TrimSpace(line), "@-")
+	}
+	return ""
+}
+
+// blockScalar returns the first line of a YAML | or > block.
+func blockScalar(lines []string) string {
+	for _, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
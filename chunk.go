@@ -0,0 +1,124 @@
+// chunk.go
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ChunkResults splits results at file boundaries so that every chunk fits
+// into config.MaxTokens, counting the banner and prompt every chunk carries
+// and the sections written with the first one. A single file larger than
+// the budget gets a chunk of its own.
+func ChunkResults(results []FileResult, sections []Section, config *Config) [][]FileResult {
+	if config.MaxTokens <= 0 || len(results) == 0 {
+		return [][]FileResult{results}
+	}
+
+	var chunks [][]FileResult
+	var current []FileResult
+	// Every chunk starts with the format's own framing.
+	framing := estimateTokens(renderFiles(nil, config), config.Tokenizer)
+	overhead := estimateTokens(GenerateOutput(nil, nil, config), config.Tokenizer)
+	used := estimateTokens(GenerateOutput(nil, sections, config), config.Tokenizer)
+
+	for _, result := range results {
+		tokens := fileTokens(result, framing, config)
+		if len(current) > 0 && used+tokens > config.MaxTokens {
+			chunks = append(chunks, current)
+			current = nil
+			used = overhead
+		}
+		if tokens > config.MaxTokens {
+			config.Debugf("File %s alone exceeds the token budget (%d > %d)", result.Path, tokens, config.MaxTokens)
+		}
+		current = append(current, result)
+		used += tokens
+	}
+
+	return append(chunks, current)
+}
+
+// fileTokens estimates the tokens result adds to an output: its own
+// rendering in the format, without the framing, banner and prompt that
+// every chunk carries once.
+func fileTokens(result FileResult, framing int, config *Config) int {
+	tokens := estimateTokens(renderFiles([]FileResult{result}, config), config.Tokenizer) - framing
+	if tokens < 0 {
+		return 0
+	}
+	return tokens
+}
+
+// renderFiles renders results in config.Format, without sections, banner
+// or prompt.
+func renderFiles(results []FileResult, config *Config) string {
+	switch config.Format {
+	case "json", "json-result":
+		return formatJSON(results, nil, config)
+	case "csv":
+		return formatDelimited(results, ',')
+	case "tsv":
+		return formatDelimited(results, '\t')
+	case "repomix":
+		return formatRepomix(results)
+	case "aider":
+		return formatAider(results)
+	case "codemap":
+		return formatCodemap(results)
+	case "report":
+		return formatReport(results, nil, config)
+	}
+	return formatText(results, config)
+}
+
+// structuredFormat reports the formats a parser reads as one document.
+func structuredFormat(format string) bool {
+	switch format {
+	case "json", "json-result", "csv", "tsv":
+		return true
+	}
+	return false
+}
+
+// chunkFileName turns output.txt into output.part2.txt.
+func chunkFileName(name string, index int) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s.part%d%s", strings.TrimSuffix(name, ext), index, ext)
+}
+
+// EmitChunks writes the output, splitting it into numbered chunks when it
+// exceeds the token budget. Sections are only written with the first chunk.
+func EmitChunks(results []FileResult, sections []Section, config *Config) error {
+	resolved := *config
+	resolved.OutputFile = expandTemplate(config.OutputFile, templateVars(config, len(results), totalTokens(results)))
+	config = &resolved
+
+	chunks := ChunkResults(results, sections, config)
+	if len(chunks) == 1 {
+		return EmitOutput(GenerateOutput(results, sections, config), config)
+	}
+	// Several JSON or CSV documents in a row on stdout no longer parse.
+	if !config.Save && structuredFormat(config.Format) {
+		return fmt.Errorf("the %s output needs %d chunks for --max-tokens %d; use --save to write one file per chunk", config.Format, len(chunks), config.MaxTokens)
+	}
+
+	for i, chunk := range chunks {
+		chunkConfig := *config
+		chunkConfig.OutputFile = chunkFileName(config.OutputFile, i+1)
+
+		var chunkSections []Section
+		if i == 0 {
+			chunkSections = sections
+		}
+
+		if !config.Save {
+			fmt.Printf("=== Chunk %d/%d ===\n", i+1, len(chunks))
+		}
+		if err := EmitOutput(GenerateOutput(chunk, chunkSections, &chunkConfig), &chunkConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
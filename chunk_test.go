@@ -0,0 +1,58 @@
+// chunk_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkResultsCountsBannerAndSections(t *testing.T) {
+	results := []FileResult{
+		{Path: "a.go", Content: strings.Repeat("a", 400)},
+		{Path: "b.go", Content: strings.Repeat("b", 400)},
+		{Path: "c.go", Content: strings.Repeat("c", 400)},
+	}
+	sections := []Section{{Title: "Notes", Content: strings.Repeat("note ", 100)}}
+
+	cases := []struct {
+		name     string
+		banner   bool
+		sections []Section
+		want     int
+	}{
+		{name: "files only", want: 1},
+		{name: "banner", banner: true, want: 2},
+		{name: "sections", sections: sections, want: 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := defaultConfig()
+			for _, result := range results {
+				config.MaxTokens += fileTokens(result, 0, config)
+			}
+			if tc.banner {
+				config.Banner = true
+				config.BannerText = strings.Repeat("banner ", 50)
+			}
+
+			if chunks := ChunkResults(results, tc.sections, config); len(chunks) != tc.want {
+				t.Errorf("got %d chunks, want %d", len(chunks), tc.want)
+			}
+		})
+	}
+}
+
+func TestEmitChunksRefusesStructuredStdout(t *testing.T) {
+	results := []FileResult{
+		{Path: "a.go", Content: strings.Repeat("a", 400)},
+		{Path: "b.go", Content: strings.Repeat("b", 400)},
+	}
+	for _, format := range []string{"json", "json-result", "csv", "tsv"} {
+		config := defaultConfig()
+		config.Format = format
+		config.MaxTokens = 120
+		if err := EmitChunks(results, nil, config); err == nil || !strings.Contains(err.Error(), "--save") {
+			t.Errorf("%s: got %v, want an error pointing to --save", format, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// classify.go
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fileClasses are the labels classifyFile assigns, in the order they are
+// listed in reports.
+var fileClasses = []string{"source", "test", "config", "docs", "data", "generated", "vendored"}
+
+var generatedMarkerRe = regexp.MustCompile(`(?im)^.{0,8}(code generated .* do not edit|@generated|auto-?generated)`)
+
+var (
+	vendoredDirs = []string{"vendor", "node_modules", "third_party", "bower_components"}
+	testDirs     = []string{"test", "tests", "__tests__", "testdata", "spec"}
+	docsDirs     = []string{"doc", "docs", "documentation"}
+)
+
+var (
+	docsExts   = map[string]bool{".md": true, ".rst": true, ".adoc": true, ".txt": true, ".org": true}
+	configExts = map[string]bool{".yaml": true, ".yml": true, ".toml": true, ".ini": true, ".cfg": true, ".conf": true, ".env": true, ".properties": true}
+	dataExts   = map[string]bool{".csv": true, ".tsv": true, ".json": true, ".jsonl": true, ".xml": true, ".sql": true, ".parquet": true}
+	docsNames  = map[string]bool{"LICENSE": true, "COPYING": true, "NOTICE": true, "AUTHORS": true, "CHANGELOG": true}
+
+	configNames = map[string]bool{
+		"Makefile": true, "Dockerfile": true, "Jenkinsfile": true, "go.mod": true,
+		"package.json": true, "tsconfig.json": true, "composer.json": true, ".editorconfig": true, ".gitignore": true,
+	}
+	generatedNames = map[string]bool{
+		"go.sum": true, "package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+		"Cargo.lock": true, "poetry.lock": true, "Gemfile.lock": true, "composer.lock": true,
+	}
+)
+
+// classifyFile labels a file by its role in the project, looking at the
+// source, the path and the first lines of content. Dependencies and
+// standard library files are always vendored.
+func classifyFile(path, source string, content []byte) string {
+	if source == "dep" || source == "std" {
+		return "vendored"
+	}
+
+	slashed := filepath.ToSlash(path)
+	dirs := strings.Split(slashed, "/")
+	base := dirs[len(dirs)-1]
+	dirs = dirs[:len(dirs)-1]
+	ext := strings.ToLower(filepath.Ext(base))
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+
+	switch {
+	case hasDir(dirs, vendoredDirs):
+		return "vendored"
+	case generatedNames[base] || isGeneratedName(base) || generatedMarkerRe.Match(head(content, 1024)):
+		return "generated"
+	case isTestName(base) || hasDir(dirs, testDirs):
+		return "test"
+	case docsNames[stem] || strings.EqualFold(stem, "README") || docsExts[ext] || hasDir(dirs, docsDirs):
+		return "docs"
+	case configNames[base] || configExts[ext] || strings.HasPrefix(slashed, ".github/") || strings.Contains(slashed, "/.github/"):
+		return "config"
+	case ext == ".json" && strings.Contains(strings.ToLower(stem), "config"):
+		return "config"
+	case dataExts[ext]:
+		return "data"
+	case detectLanguage(path) != "":
+		return "source"
+	}
+	return "data"
+}
+
+func isGeneratedName(base string) bool {
+	for _, suffix := range []string{".pb.go", "_gen.go", ".gen.go", "_generated.go", ".min.js", ".min.css", ".map"} {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTestName(base string) bool {
+	if strings.HasSuffix(base, "_test.go") || strings.HasSuffix(base, "_test.py") || strings.HasPrefix(base, "test_") {
+		return true
+	}
+	for _, infix := range []string{".test.", ".spec.", "_spec."} {
+		if strings.Contains(base, infix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDir(dirs []string, names []string) bool {
+	for _, dir := range dirs {
+		for _, name := range names {
+			if dir == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func head(content []byte, n int) []byte {
+	if len(content) > n {
+		content = content[:n]
+	}
+	return bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+}
+
+func isValidClass(class string) bool {
+	for _, c := range fileClasses {
+		if class == c {
+			return true
+		}
+	}
+	return false
+}
+
+// filterClasses keeps the results whose class is listed in
+// config.OnlyClasses; an empty list keeps everything.
+func filterClasses(results []FileResult, config *Config) []FileResult {
+	if len(config.OnlyClasses) == 0 {
+		return results
+	}
+	allowed := make(map[string]bool)
+	for _, class := range config.OnlyClasses {
+		allowed[class] = true
+	}
+
+	var kept []FileResult
+	for _, result := range results {
+		if allowed[result.Class] {
+			kept = append(kept, result)
+		} else {
+			config.Debugf("Ignoring %s file: %s", result.Class, result.Path)
+		}
+	}
+	return kept
+}
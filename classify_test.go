@@ -0,0 +1,33 @@
+// classify_test.go
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestClassesIgnoreTheWalkedDirectory(t *testing.T) {
+	config := defaultConfig()
+	config.Dirs = []string{filepath.Join("testdata", "corpus")}
+	results, err := ProcessFiles(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"testdata/corpus/README.md":        "docs",
+		"testdata/corpus/main.go":          "source",
+		"testdata/corpus/docs/guide.md":    "docs",
+		"testdata/corpus/scripts/build.sh": "source",
+	}
+	for _, result := range results {
+		if class, ok := want[result.Path]; ok {
+			if result.Class != class {
+				t.Errorf("%s is %s, want %s", result.Path, result.Class, class)
+			}
+			delete(want, result.Path)
+		}
+	}
+	for path := range want {
+		t.Errorf("%s was not walked", path)
+	}
+}
@@ -0,0 +1,133 @@
+// codemap.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxSummaryLength = 100
+
+func formatCodemap(results []FileResult) string {
+	var buffer bytes.Buffer
+
+	for _, result := range results {
+		buffer.WriteString(fmt.Sprintf("%s: %s\n", filepath.ToSlash(result.Path), summarizeFile(result.Path, result.Content)))
+	}
+
+	return buffer.String()
+}
+
+// summarizeFile produces a one-line description of a file, preferring doc
+// comments and falling back to the first meaningful line.
+func summarizeFile(path, content string) string {
+	if isGoFile(path) {
+		if summary := summarizeGoFile(path, content); summary != "" {
+			return truncateSummary(summary)
+		}
+	}
+	if summary := summarizeText(path, content); summary != "" {
+		return truncateSummary(summary)
+	}
+	return "(empty)"
+}
+
+func summarizeGoFile(path, content string) string {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return ""
+	}
+
+	if node.Doc != nil && strings.TrimSpace(node.Doc.Text()) != filepath.Base(path) {
+		return firstSentence(node.Doc.Text())
+	}
+
+	var names []string
+	for _, decl := range node.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				return firstSentence(d.Doc.Text())
+			}
+			if d.Name.IsExported() {
+				names = append(names, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil && d.Tok == token.TYPE {
+				return firstSentence(d.Doc.Text())
+			}
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.IsExported() {
+					names = append(names, ts.Name.Name)
+				}
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Sprintf("package %s", node.Name.Name)
+	}
+	if len(names) > 5 {
+		names = append(names[:5], "...")
+	}
+	return fmt.Sprintf("package %s: %s", node.Name.Name, strings.Join(names, ", "))
+}
+
+func summarizeText(path, content string) string {
+	base := filepath.Base(path)
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#!") {
+			continue
+		}
+		for _, marker := range []string{"//", "/*", "<!--", "#", "--", ";", "*"} {
+			line = strings.TrimSpace(strings.TrimPrefix(line, marker))
+		}
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(line, "-->"), "*/"))
+		// Skip "// main.go" style headers that only repeat the file name.
+		if line == base || !strings.ContainsFunc(line, isAlphanumeric) {
+			continue
+		}
+		return line
+	}
+
+	return ""
+}
+
+func isAlphanumeric(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+func truncateSummary(s string) string {
+	return ellipsize(s, maxSummaryLength)
+}
+
+// ellipsize shortens s to at most max bytes, ending in "...", without
+// splitting a UTF-8 sequence.
+func ellipsize(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - 3
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
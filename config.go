@@ -3,53 +3,335 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	Dirs        []string
-	IgnoreFiles []string
-	IgnoreDirs  []string
-	IgnoreExts  []string
-	IncludeExts []string
-	Recursive   bool
-	Debug       bool
-	Save        bool
-	OutputFile  string
-	ShowSize    bool
-	ShowFuncs   bool
+	Version          int      `json:"version,omitempty"`
+	Dirs             []string `json:"dirs,omitempty"`
+	IgnoreFiles      []string `json:"ignore_files,omitempty"`
+	IgnoreDirs       []string `json:"ignore_dirs,omitempty"`
+	IgnoreExts       []string `json:"ignore_exts,omitempty"`
+	IncludeExts      []string `json:"include_exts,omitempty"`
+	IncludeDeps      []string `json:"include_deps,omitempty"`
+	IncludeStd       []string `json:"include_std,omitempty"`
+	OnlyClasses      []string `json:"only_classes,omitempty"`
+	GOOS             string   `json:"goos,omitempty"`
+	GOARCH           string   `json:"goarch,omitempty"`
+	MaxFileSize      int64    `json:"max_file_size,omitempty"`
+	WrapColumn       int      `json:"wrap_column,omitempty"`
+	WrapMarker       string   `json:"wrap_marker,omitempty"`
+	DedupeLicenses   bool     `json:"dedupe_licenses,omitempty"`
+	NormalizeData    bool     `json:"normalize_data,omitempty"`
+	MaxArrayItems    int      `json:"max_array_items,omitempty"`
+	Migrations       bool     `json:"consolidate_migrations,omitempty"`
+	Recursive        bool     `json:"recursive"`
+	OneFileSystem    bool     `json:"one_file_system,omitempty"`
+	NetworkFS        bool     `json:"network_fs,omitempty"`
+	Debug            bool     `json:"debug,omitempty"`
+	Save             bool     `json:"save,omitempty"`
+	Tail             bool     `json:"tail,omitempty"`
+	Workers          int      `json:"workers,omitempty"`
+	MinWorkers       int      `json:"min_workers,omitempty"`
+	MaxWorkers       int      `json:"max_workers,omitempty"`
+	OutputFile       string   `json:"output_file,omitempty"`
+	Sinks            []string `json:"sinks,omitempty"`
+	ShowSize         bool     `json:"show_size,omitempty"`
+	ShowFuncs        bool     `json:"show_funcs,omitempty"`
+	History          bool     `json:"history,omitempty"`
+	SuggestIgnores   bool     `json:"suggest_ignores,omitempty"`
+	ApplySuggestions bool     `json:"-"`
+	Format           string   `json:"format,omitempty"`
+	FileHeader       string   `json:"file_header,omitempty"`
+	FileFooter       string   `json:"file_footer,omitempty"`
+	Banner           bool     `json:"banner,omitempty"`
+	BannerText       string   `json:"banner_text,omitempty"`
+	GitLog           int      `json:"git_log,omitempty"`
+	GitLogFiles      bool     `json:"git_log_files,omitempty"`
+	Coverage         string   `json:"coverage,omitempty"`
+	ModuleSummaries  bool     `json:"module_summaries,omitempty"`
+	BuildSection     bool     `json:"build_section,omitempty"`
+	Routes           bool     `json:"routes,omitempty"`
+	EnvVars          bool     `json:"env_vars,omitempty"`
+
+	// Priorities weigh files by directory, glob or source; heavier ones
+	// come first in the output. See prioritizeResults.
+	Priorities map[string]int `json:"priorities,omitempty"`
+
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	PromptDetails  string `json:"prompt_details,omitempty"`
+
+	Model     string `json:"model,omitempty"`
+	Tokenizer string `json:"tokenizer,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+	Prune     bool   `json:"prune,omitempty"`
+
+	Delta    bool   `json:"delta,omitempty"`
+	Manifest string `json:"manifest,omitempty"`
+
+	// Aliases map a name to the arguments it stands for; see expandAlias.
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	RunID      string `json:"-"`
+	Source     string `json:"-"`
+	Profile    string `json:"-"`
+	ConfigFile string `json:"-"`
+	SaveConfig string `json:"-"`
+
+	// Logger receives debug output; nil writes it to stderr.
+	Logger *Logger `json:"-"`
+	// Warnings collects the problems that did not stop the run.
+	Warnings *Warnings `json:"-"`
+
+	// tail streams the output file of a --tail run.
+	tail *tailWriter
+	// started is when the run began, for RunResult.
+	started time.Time
+	// personal holds the per-user defaults still in effect; nil without a
+	// defaults file. See stripUserDefaults.
+	personal *Config
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Dirs:       []string{"."},
+		Recursive:  true,
+		OutputFile: "output.txt",
+		Format:     "text",
+		FileHeader: "File: {path}",
+		WrapMarker: " \\",
+		BannerText: defaultBannerText,
+		Warnings:   &Warnings{},
+	}
 }
 
-func ParseFlags() *Config {
-	config := &Config{}
+func ParseFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	config := defaultConfig()
+	base, err := userDefaults()
+	if err != nil {
+		return nil, err
+	}
 
-	dirFlag := flag.String("dir", ".", "Comma-separated list of directories to search (default: current directory)")
-	ignoreFileFlag := flag.String("ignore-file", "", "Comma-separated list of files to ignore")
-	ignoreDirFlag := flag.String("ignore-dir", "", "Comma-separated list of directories to ignore")
-	ignoreExtFlag := flag.String("ignore-ext", "", "Comma-separated list of file extensions to ignore")
-	includeExtFlag := flag.String("include-ext", "", "Comma-separated list of file extensions to include")
-	recursiveFlag := flag.Bool("recursive", true, "Recursively search directories (default: true)")
-	debugFlag := flag.Bool("debug", false, "Enable debug output")
-	saveFlag := flag.Bool("save", false, "Save the output to a file")
-	outputFileFlag := flag.String("output-file", "output.txt", "Specify the output file name (default: output.txt)")
-	showSizeFlag := flag.Bool("show-size", false, "Show the size of the result in bytes")
-	showFuncsFlag := flag.Bool("show-funcs", false, "Show only functions and their parameters")
+	// The config file provides the defaults, so it has to be loaded before
+	// the remaining flags are defined.
+	if path := flagValueFromArgs(args, "config"); path != "" {
+		loaded, err := LoadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		base = loaded
+	}
 
-	flag.Parse()
+	configFlag := fs.String("config", "", "Load settings from a config file (codexgigantus JSON, repomix.config.json or .gitingest)")
+	runIDFlag := fs.String("run-id", "", "ID recorded in logs, banner and JSON output (default: generated, or $CODEXGIGANTUS_RUN_ID)")
+	saveConfigFlag := fs.String("save-config", "", "Write the effective settings to a JSON config file and exit")
+	dirFlag := fs.String("dir", strings.Join(base.Dirs, ","), "Comma-separated list of directories to search (default: current directory)")
+	ignoreFileFlag := fs.String("ignore-file", strings.Join(base.IgnoreFiles, ","), "Comma-separated list of files to ignore")
+	ignoreDirFlag := fs.String("ignore-dir", strings.Join(base.IgnoreDirs, ","), "Comma-separated list of directories to ignore")
+	ignoreExtFlag := fs.String("ignore-ext", strings.Join(base.IgnoreExts, ","), "Comma-separated list of file extensions to ignore")
+	includeExtFlag := fs.String("include-ext", strings.Join(base.IncludeExts, ","), "Comma-separated list of file extensions to include")
+	includeDepsFlag := fs.String("include-deps", strings.Join(base.IncludeDeps, ","), "Comma-separated list of Go dependencies to include, as import/path[@version]")
+	includeStdFlag := fs.String("include-std", strings.Join(base.IncludeStd, ","), "Comma-separated list of standard library packages or symbols to include, e.g. net/http.Server")
+	onlyClassFlag := fs.String("only-class", strings.Join(base.OnlyClasses, ","), "Comma-separated list of file classes to include: "+strings.Join(fileClasses, ", "))
+	goosFlag := fs.String("goos", base.GOOS, "Only include Go files built for this GOOS (file name suffix and build constraints)")
+	goarchFlag := fs.String("goarch", base.GOARCH, "Only include Go files built for this GOARCH")
+	maxFileSizeFlag := fs.Int64("max-file-size", base.MaxFileSize, "Truncate files larger than this many bytes (0 disables)")
+	wrapFlag := fs.Int("wrap", base.WrapColumn, "Hard-wrap lines longer than this many characters (0 disables)")
+	wrapMarkerFlag := fs.String("wrap-marker", base.WrapMarker, "Continuation marker appended to wrapped line pieces")
+	dedupeLicensesFlag := fs.Bool("dedupe-licenses", base.DedupeLicenses, "Replace license headers repeated across files with a note pointing at the first copy")
+	normalizeDataFlag := fs.Bool("normalize-data", base.NormalizeData, "Rewrite JSON, YAML and TOML files with sorted keys and without blank lines, so runs diff cleanly")
+	maxArrayItemsFlag := fs.Int("max-array-items", base.MaxArrayItems, "With --normalize-data, keep only the first N items of longer arrays (0 keeps all)")
+	migrationsFlag := fs.Bool("consolidate-migrations", base.Migrations, "Replace schema migration directories (goose, golang-migrate, Rails, Prisma) with the schema they produce")
+	priorityFlag := fs.String("priority", formatPriorities(base.Priorities), "Comma-separated pattern=weight pairs ordering the output, heaviest first, e.g. docs=10,internal/core=5,*_test.go=-10")
+	recursiveFlag := fs.Bool("recursive", base.Recursive, "Recursively search directories (default: true)")
+	oneFileSystemFlag := fs.Bool("one-file-system", base.OneFileSystem, "Do not descend into directories on other filesystems (mounts, network shares)")
+	networkFSFlag := fs.Bool("network-fs", base.NetworkFS, "Optimise for NFS/SMB shares: fewer metadata calls, more reads in flight, retries on transient errors")
+	debugFlag := fs.Bool("debug", base.Debug, "Enable debug output")
+	saveFlag := fs.Bool("save", base.Save, "Save the output to a file")
+	workersFlag := fs.Int("workers", base.Workers, "Files read and processed in parallel (0 adapts to the CPUs and read latency)")
+	minWorkersFlag := fs.Int("min-workers", base.MinWorkers, "Lower bound of the adaptive worker count (default 1)")
+	maxWorkersFlag := fs.Int("max-workers", base.MaxWorkers, "Upper bound of the adaptive worker count (default 4 per CPU)")
+	tailFlag := fs.Bool("tail", base.Tail, "Write the output file while files are read and print progress, so tail -f can follow it (text format)")
+	outputFileFlag := fs.String("output-file", base.OutputFile, "Specify the output file name (default: output.txt)")
+	sinkFlag := fs.String("sink", strings.Join(base.Sinks, ","), "Comma-separated list of extra destinations: gdrive:<folder-id>, onedrive:<folder>, email:<to;to>, slack:<channel-id>")
+	showSizeFlag := fs.Bool("show-size", base.ShowSize, "Show the size of the result in bytes")
+	showFuncsFlag := fs.Bool("show-funcs", base.ShowFuncs, "Show only functions and their parameters")
+	historyFlag := fs.Bool("history", base.History, "Record the run's stats in the local history file (see codexgigantus history)")
+	suggestIgnoresFlag := fs.Bool("suggest-ignores", base.SuggestIgnores, "After the run, suggest ignore rules for vendored, generated and data files that cost many tokens")
+	applySuggestionsFlag := fs.Bool("apply-suggestions", false, "Add the suggested ignore rules to the --config file")
+	formatFlag := fs.String("format", base.Format, "Output format: text, json, csv, tsv, repomix, aider, codemap, report or json-result")
+	fileHeaderFlag := fs.String("file-header", base.FileHeader, "Line written before each file in the text format; supports {path}, {language}, {class}, {size} and {tokens}")
+	fileFooterFlag := fs.String("file-footer", base.FileFooter, "Line written after each file in the text format (default: none)")
+	bannerFlag := fs.Bool("banner", base.Banner, "Prepend a comment-header banner describing the output")
+	bannerTextFlag := fs.String("banner-text", base.BannerText, "Banner template; supports {time}, {host}, {run_id}, {files} and {tokens}")
+	gitLogFlag := fs.Int("git-log", base.GitLog, "Include the last N commit messages as a section (0 disables)")
+	gitLogFilesFlag := fs.Bool("git-log-files", base.GitLogFiles, "Only include commits that touch the included files")
+	buildSectionFlag := fs.Bool("build-section", base.BuildSection, "Put build and CI files (Makefile, Dockerfile, workflows, Taskfile) first and summarise them in a \"How this project builds\" section")
+	routesFlag := fs.Bool("routes", base.Routes, "Add an \"API routes\" section listing the HTTP routes and their handlers")
+	envVarsFlag := fs.Bool("env-vars", base.EnvVars, "Add an \"Environment variables\" section listing the variables the code reads, checked against .env.example")
+	moduleSummariesFlag := fs.Bool("module-summaries", base.ModuleSummaries, "Add a summary section per Go module: module path, requirements and exported packages")
+	coverageFlag := fs.String("coverage", base.Coverage, "Go cover profile or lcov file; adds a coverage section and per-file coverage")
+	promptTemplateFlag := fs.String("prompt-template", base.PromptTemplate, "Wrap the output in a task prompt: "+strings.Join(promptTemplateNames(), ", "))
+	promptDetailsFlag := fs.String("prompt-details", base.PromptDetails, "Task details inserted into the prompt template, e.g. the bug description")
+	modelFlag := fs.String("model", base.Model, "Target model; sets the tokenizer and token budget (e.g. gpt-4o, claude-3-5-sonnet, or a local Ollama model)")
+	tokenizerFlag := fs.String("tokenizer", base.Tokenizer, "Tokenizer used for token estimates: cl100k, o200k, claude, gemini or llama")
+	maxTokensFlag := fs.Int("max-tokens", base.MaxTokens, "Token budget per output; larger outputs are split into chunks (0 disables)")
+	pruneFlag := fs.Bool("prune", base.Prune, "When over the token budget, pick the directories and files to leave out interactively")
+	deltaFlag := fs.Bool("delta", base.Delta, "Only emit the files changed since the previous --delta run, plus a list of the unchanged ones")
+	manifestFlag := fs.String("manifest", base.Manifest, "Manifest recording the files of the previous --delta run (default: per directory set in the user config dir)")
 
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.ConfigFile = *configFlag
+	config.RunID = *runIDFlag
+	if config.RunID == "" {
+		config.RunID = newRunID()
+	}
+	config.Profile = base.Profile
+	config.Aliases = base.Aliases
+	config.personal = base.personal
+	config.SaveConfig = *saveConfigFlag
 	config.Dirs = parseCommaSeparated(*dirFlag)
 	config.IgnoreFiles = parseCommaSeparated(*ignoreFileFlag)
 	config.IgnoreDirs = parseCommaSeparated(*ignoreDirFlag)
 	config.IgnoreExts = parseCommaSeparated(*ignoreExtFlag)
 	config.IncludeExts = parseCommaSeparated(*includeExtFlag)
+	config.IncludeDeps = parseCommaSeparated(*includeDepsFlag)
+	config.IncludeStd = parseCommaSeparated(*includeStdFlag)
+	config.OnlyClasses = parseCommaSeparated(*onlyClassFlag)
+	config.GOOS = *goosFlag
+	config.GOARCH = *goarchFlag
+	config.MaxFileSize = *maxFileSizeFlag
+	config.WrapColumn = *wrapFlag
+	config.WrapMarker = *wrapMarkerFlag
+	config.DedupeLicenses = *dedupeLicensesFlag
+	config.NormalizeData = *normalizeDataFlag
+	config.MaxArrayItems = *maxArrayItemsFlag
+	config.Migrations = *migrationsFlag
+	if config.Priorities, err = parsePriorities(*priorityFlag); err != nil {
+		return nil, err
+	}
 	config.Recursive = *recursiveFlag
+	config.OneFileSystem = *oneFileSystemFlag
+	config.NetworkFS = *networkFSFlag
 	config.Debug = *debugFlag
 	config.Save = *saveFlag
+	config.Tail = *tailFlag
+	config.Workers = *workersFlag
+	config.MinWorkers = *minWorkersFlag
+	config.MaxWorkers = *maxWorkersFlag
 	config.OutputFile = *outputFileFlag
+	config.Sinks = parseCommaSeparated(*sinkFlag)
 	config.ShowSize = *showSizeFlag
 	config.ShowFuncs = *showFuncsFlag
+	config.History = *historyFlag
+	config.SuggestIgnores = *suggestIgnoresFlag || *applySuggestionsFlag
+	config.ApplySuggestions = *applySuggestionsFlag
+	config.Format = *formatFlag
+	config.FileHeader = *fileHeaderFlag
+	config.FileFooter = *fileFooterFlag
+	config.Banner = *bannerFlag
+	config.BannerText = *bannerTextFlag
+	config.GitLog = *gitLogFlag
+	config.GitLogFiles = *gitLogFilesFlag
+	config.BuildSection = *buildSectionFlag
+	config.Routes = *routesFlag
+	config.EnvVars = *envVarsFlag
+	config.ModuleSummaries = *moduleSummariesFlag
+	config.Coverage = *coverageFlag
+	config.PromptTemplate = *promptTemplateFlag
+	config.PromptDetails = *promptDetailsFlag
+	config.Model = *modelFlag
+	config.Tokenizer = *tokenizerFlag
+	config.MaxTokens = *maxTokensFlag
+	config.Prune = *pruneFlag
+	config.Delta = *deltaFlag
+	config.Manifest = *manifestFlag
+
+	return config, nil
+}
 
-	return config
+// configRules are the settings a run refuses to start with. ValidateConfig
+// stops at the first rule that fails; config validate reports them all,
+// under the config file field they concern.
+var configRules = []struct {
+	field string
+	check func(config *Config) error
+}{
+	{"format", func(config *Config) error {
+		if !isValidFormat(config.Format) {
+			return fmt.Errorf("unknown output format: %s", config.Format)
+		}
+		return nil
+	}},
+	{"prompt_template", func(config *Config) error {
+		if !isValidPromptTemplate(config.PromptTemplate) {
+			return fmt.Errorf("unknown prompt template: %s", config.PromptTemplate)
+		}
+		return nil
+	}},
+	{"tail", func(config *Config) error {
+		if config.Tail && (!config.Save || config.Format != "text" || config.MaxTokens > 0) {
+			return fmt.Errorf("--tail needs --save and the text format, and cannot be combined with --max-tokens")
+		}
+		if config.Tail && config.Delta {
+			return fmt.Errorf("--tail cannot be combined with --delta")
+		}
+		return nil
+	}},
+	{"tokenizer", func(config *Config) error {
+		if !isValidTokenizer(config.Tokenizer) {
+			return fmt.Errorf("unknown tokenizer: %s", config.Tokenizer)
+		}
+		return nil
+	}},
+	{"only_classes", func(config *Config) error {
+		for _, class := range config.OnlyClasses {
+			if !isValidClass(class) {
+				return fmt.Errorf("unknown file class: %s", class)
+			}
+		}
+		return nil
+	}},
+	{"sinks", func(config *Config) error {
+		return validateSinks(config.Sinks)
+	}},
+}
+
+// ValidateConfig checks the settings that name built-in formats, templates,
+// tokenizers and sinks, and the combinations of flags that cannot work.
+func ValidateConfig(config *Config) error {
+	for _, rule := range configRules {
+		if err := rule.check(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagValueFromArgs finds the value of a string flag without parsing the
+// full flag set, accepting the -name value, -name=value and -- forms.
+func flagValueFromArgs(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		trimmed := strings.TrimLeft(arg, "-")
+		if trimmed == arg {
+			continue
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(trimmed, name+"=") {
+			return strings.TrimPrefix(trimmed, name+"=")
+		}
+	}
+	return ""
 }
 
 func parseCommaSeparated(s string) []string {
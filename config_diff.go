@@ -0,0 +1,71 @@
+// config_diff.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// runConfigDiff prints the settings that differ between two config files
+// after both have been loaded and filled in with the defaults, so a
+// repomix config can be compared with a codexgigantus profile as well.
+func runConfigDiff(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: codexgigantus config diff a.json b.json")
+	}
+
+	a, err := configFields(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := configFields(args[1])
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]bool{}
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	var sorted []string
+	for key := range keys {
+		if string(a[key]) != string(b[key]) {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+
+	if len(sorted) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+	fmt.Printf("--- %s\n+++ %s\n", args[0], args[1])
+	for _, key := range sorted {
+		if value, ok := a[key]; ok {
+			fmt.Printf("- %s: %s\n", key, value)
+		}
+		if value, ok := b[key]; ok {
+			fmt.Printf("+ %s: %s\n", key, value)
+		}
+	}
+	return nil
+}
+
+func configFields(path string) (map[string]json.RawMessage, error) {
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = configVersion
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	return fields, json.Unmarshal(data, &fields)
+}
@@ -0,0 +1,184 @@
+// config_file.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type repomixConfig struct {
+	Output struct {
+		FilePath   string `json:"filePath"`
+		Style      string `json:"style"`
+		HeaderText string `json:"headerText"`
+	} `json:"output"`
+	Include []string `json:"include"`
+	Ignore  struct {
+		CustomPatterns []string `json:"customPatterns"`
+	} `json:"ignore"`
+}
+
+// configVersion is the schema version written by --save-config. Files
+// without a version predate versioning and are read as the current one;
+// once a field is renamed, the version goes up and loading older files
+// has to translate it.
+const configVersion = 1
+
+var (
+	gitingestPatternsRe = regexp.MustCompile(`(?s)ignore_patterns\s*=\s*\[(.*?)\]`)
+	quotedStringRe      = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// LoadConfigFile reads a codexgigantus JSON config. repomix.config.json and
+// .gitingest files are recognised by name and converted on the fly.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	config, err := userDefaults()
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	switch name := filepath.Base(path); {
+	case name == "repomix.config.json":
+		err = convertRepomixConfig(data, config)
+	case name == ".gitingest" || strings.HasSuffix(name, ".toml"):
+		err = convertGitingestConfig(data, config)
+	default:
+		if err = checkConfigVersion(data); err == nil {
+			err = json.Unmarshal(data, config)
+		}
+		if err == nil {
+			err = json.Unmarshal(data, &fields)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	overrideUserDefaults(config, fields)
+
+	config.Profile = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return config, nil
+}
+
+// checkConfigVersion rejects configs written by a newer release, whose
+// fields this one may read wrongly.
+func checkConfigVersion(data []byte) error {
+	var fields struct {
+		Version *json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	version := 0
+	if fields.Version != nil {
+		if err := json.Unmarshal(*fields.Version, &version); err != nil {
+			return fmt.Errorf("invalid version: %w", err)
+		}
+	}
+	if version > configVersion {
+		return fmt.Errorf("config version %d is newer than this release supports (%d); upgrade codexgigantus", version, configVersion)
+	}
+	return nil
+}
+
+func SaveConfigFile(path string, config *Config) error {
+	saved := *config
+	stripUserDefaults(&saved)
+	saved.Version = configVersion
+	config = &saved
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func convertRepomixConfig(data []byte, config *Config) error {
+	var rc repomixConfig
+	if err := json.Unmarshal(data, &rc); err != nil {
+		return err
+	}
+
+	if rc.Output.FilePath != "" {
+		config.Save = true
+		config.OutputFile = rc.Output.FilePath
+	}
+	if rc.Output.Style == "xml" || rc.Output.Style == "" {
+		config.Format = "repomix"
+	}
+	if rc.Output.HeaderText != "" {
+		config.Banner = true
+		config.BannerText = rc.Output.HeaderText
+	}
+	for _, pattern := range rc.Include {
+		if ext, ok := extensionFromPattern(pattern); ok {
+			config.IncludeExts = append(config.IncludeExts, ext)
+		}
+	}
+	for _, pattern := range rc.Ignore.CustomPatterns {
+		addIgnorePattern(config, pattern)
+	}
+
+	return nil
+}
+
+func convertGitingestConfig(data []byte, config *Config) error {
+	match := gitingestPatternsRe.FindSubmatch(data)
+	if match == nil {
+		return nil
+	}
+	for _, quoted := range quotedStringRe.FindAllSubmatch(match[1], -1) {
+		pattern := string(quoted[1])
+		if pattern == "" {
+			pattern = string(quoted[2])
+		}
+		addIgnorePattern(config, pattern)
+	}
+
+	return nil
+}
+
+// addIgnorePattern maps a glob-style ignore pattern onto the closest
+// ignore list. Patterns that cannot be expressed are reported and skipped.
+func addIgnorePattern(config *Config, pattern string) {
+	pattern = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(pattern), "./"), "**/")
+
+	if ext, ok := extensionFromPattern(pattern); ok {
+		config.IgnoreExts = append(config.IgnoreExts, ext)
+		return
+	}
+
+	for _, suffix := range []string{"/**", "/*", "/"} {
+		if dir := strings.TrimSuffix(pattern, suffix); dir != pattern && !strings.ContainsAny(dir, "*?[") {
+			config.IgnoreDirs = append(config.IgnoreDirs, dir)
+			return
+		}
+	}
+
+	if pattern != "" && !strings.ContainsAny(pattern, "*?[/") {
+		config.IgnoreFiles = append(config.IgnoreFiles, pattern)
+		return
+	}
+
+	config.Warn("ignore-pattern", "", "skipping unsupported ignore pattern %s", pattern)
+}
+
+func extensionFromPattern(pattern string) (string, bool) {
+	pattern = strings.TrimPrefix(pattern, "**/")
+	if !strings.HasPrefix(pattern, "*.") {
+		return "", false
+	}
+	ext := strings.TrimPrefix(pattern, "*.")
+	if ext == "" || strings.ContainsAny(ext, "*?[/{") {
+		return "", false
+	}
+	return ext, true
+}
@@ -0,0 +1,77 @@
+// config_file_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertRepomixIgnorePatterns(t *testing.T) {
+	config := defaultConfig()
+	config.IgnoreDirs, config.IgnoreExts, config.IgnoreFiles = nil, nil, nil
+	config.Warnings = &Warnings{}
+	data := `{"ignore": {"customPatterns": ["**/*.log", "dist/**", "Makefile", "src/*_gen.go"]}}`
+	if err := convertRepomixConfig([]byte(data), config); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(config.IgnoreExts, ",") + "|" + strings.Join(config.IgnoreDirs, ",") + "|" + strings.Join(config.IgnoreFiles, ",")
+	if got != "log|dist|Makefile" {
+		t.Errorf("ignore lists are %s, want log|dist|Makefile", got)
+	}
+	warnings := config.Warnings.List()
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "src/*_gen.go") {
+		t.Errorf("warnings %v, want one for src/*_gen.go", warnings)
+	}
+}
+
+func TestCheckConfigVersion(t *testing.T) {
+	cases := []struct {
+		data string
+		err  string
+	}{
+		{data: `{"format": "json"}`},
+		{data: `{"version": 1}`},
+		{data: `{"version": 2}`, err: "newer than this release"},
+		{data: `{"version": "1"}`, err: "invalid version"},
+	}
+	for _, tc := range cases {
+		err := checkConfigVersion([]byte(tc.data))
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("checkConfigVersion(%s) = %v, want %q", tc.data, err, tc.err)
+		}
+	}
+}
+
+func TestConfigFieldsAcrossFormats(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_DEFAULTS", "none")
+	dir := t.TempDir()
+	files := map[string]string{
+		"profile.json":        `{"version": 1, "ignore_exts": ["log"], "ignore_dirs": ["dist"]}`,
+		"repomix.config.json": `{"output": {"style": "xml"}, "ignore": {"customPatterns": ["**/*.log", "dist/**"]}}`,
+		".gitingest":          `[config]\nignore_patterns = ["*.log", "dist/"]\n`,
+		"other.json":          `{"ignore_exts": ["log"], "ignore_dirs": ["build"]}`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fields := map[string]string{}
+	for name := range files {
+		got, err := configFields(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		fields[name] = string(got["ignore_exts"]) + " " + string(got["ignore_dirs"])
+	}
+	for _, name := range []string{"repomix.config.json", ".gitingest"} {
+		if fields[name] != fields["profile.json"] {
+			t.Errorf("%s reads as %s, want %s", name, fields[name], fields["profile.json"])
+		}
+	}
+	if fields["other.json"] == fields["profile.json"] {
+		t.Errorf("different ignore_dirs compare equal: %s", fields["other.json"])
+	}
+}
@@ -0,0 +1,337 @@
+// config_validate.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// configProblem is one row of the config validate table. Severity is
+// "error" for settings that make a run fail and "warning" otherwise.
+type configProblem struct {
+	Severity string
+	Field    string
+	Message  string
+}
+
+// sinkEnv lists the environment variables each sink cannot work without.
+var sinkEnv = map[string][]string{
+	"gdrive":   {"GOOGLE_DRIVE_TOKEN"},
+	"onedrive": {"ONEDRIVE_TOKEN"},
+	"email":    {"SMTP_HOST"},
+	"slack":    {"SLACK_BOT_TOKEN"},
+}
+
+// sinkChecks test-connect a sink without delivering anything.
+var sinkChecks = map[string]func(target string) error{
+	"gdrive":   checkGoogleDrive,
+	"onedrive": checkOneDrive,
+	"email":    checkSMTP,
+	"slack":    checkSlack,
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: codexgigantus config validate|diff [flags]")
+	}
+
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "diff":
+		return runConfigDiff(args[1:])
+	default:
+		return fmt.Errorf("unknown config command: %s", args[0])
+	}
+}
+
+// runConfigValidate loads a config file the way --config does, applies any
+// further flags and reports every problem found instead of stopping at the
+// first one.
+func runConfigValidate(args []string) error {
+	var file string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file, args = args[0], append([]string{"-config", args[0]}, args[1:]...)
+	}
+
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	connectivityFlag := fs.Bool("check-connectivity", false, "Test-connect the configured sinks and Ollama models")
+
+	var problems []configProblem
+	if path := defaultsPath(); path != "" {
+		for _, problem := range checkConfigFile(path) {
+			problem.Field = filepath.Base(path) + ": " + problem.Field
+			problems = append(problems, problem)
+		}
+	}
+	if file != "" {
+		problems = append(problems, checkConfigFile(file)...)
+	}
+
+	config, err := ParseFlags(fs, args)
+	if err != nil {
+		problems = append(problems, configProblem{"error", "config", err.Error()})
+	} else {
+		problems = append(problems, checkConfig(config)...)
+		if *connectivityFlag {
+			problems = append(problems, checkConnectivity(config)...)
+		}
+	}
+
+	name := file
+	if name == "" {
+		name = "the configuration"
+	}
+	if len(problems) == 0 {
+		fmt.Printf("No problems found in %s\n", name)
+		return nil
+	}
+
+	errorCount := 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SEVERITY\tFIELD\tPROBLEM")
+	for _, problem := range problems {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", problem.Severity, problem.Field, problem.Message)
+		if problem.Severity == "error" {
+			errorCount++
+		}
+	}
+	writer.Flush()
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d error(s) in %s", errorCount, name)
+	}
+	return nil
+}
+
+// checkConfigFile reports fields of a codexgigantus JSON config that are
+// not recognised, which usually are typos that silently fall back to the
+// default. Converted repomix and gitingest files are not checked.
+func checkConfigFile(path string) []configProblem {
+	base := filepath.Base(path)
+	if base == "repomix.config.json" || base == ".gitingest" || strings.HasSuffix(base, ".toml") {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if checkConfigVersion(data) != nil || json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+	known := map[string]bool{}
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name, ok := configFieldName(configType.Field(i)); ok {
+			known[strings.ToLower(name)] = true
+		}
+	}
+	var unknown []string
+	for name := range fields {
+		// encoding/json matches keys case-insensitively, so "Format" is
+		// not a typo.
+		if !known[strings.ToLower(name)] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	var problems []configProblem
+	for _, name := range unknown {
+		problems = append(problems, configProblem{"warning", name, "unknown field, ignored"})
+	}
+	return problems
+}
+
+func checkConfig(config *Config) []configProblem {
+	var problems []configProblem
+	add := func(severity, field, format string, args ...interface{}) {
+		problems = append(problems, configProblem{severity, field, fmt.Sprintf(format, args...)})
+	}
+
+	// The rules a run enforces, so validate never passes a config that
+	// fails to run.
+	for _, rule := range configRules {
+		if err := rule.check(config); err != nil {
+			add("error", rule.field, "%v", err)
+		}
+	}
+	if config.Model != "" {
+		if _, ok := modelRegistry[config.Model]; !ok {
+			add("warning", "model", "%s is not a known model; it has to be installed in Ollama", config.Model)
+		}
+	}
+
+	for i, dir := range config.Dirs {
+		info, err := os.Stat(dir)
+		switch {
+		case err != nil:
+			add("error", fmt.Sprintf("dirs[%d]", i), "%s does not exist", dir)
+		case !info.IsDir():
+			add("error", fmt.Sprintf("dirs[%d]", i), "%s is not a directory", dir)
+		}
+	}
+	for _, ext := range config.IncludeExts {
+		for _, ignored := range config.IgnoreExts {
+			if ext == ignored {
+				add("warning", "ignore_exts", "%s is both included and ignored, so it is ignored", ext)
+			}
+		}
+	}
+
+	if config.MaxFileSize < 0 {
+		add("error", "max_file_size", "must not be negative")
+	}
+	if config.WrapColumn < 0 {
+		add("error", "wrap_column", "must not be negative")
+	}
+	if config.MaxArrayItems < 0 {
+		add("error", "max_array_items", "must not be negative")
+	}
+	if config.MaxTokens < 0 {
+		add("error", "max_tokens", "must not be negative")
+	}
+	if config.Workers < 0 {
+		add("error", "workers", "must not be negative")
+	}
+	if config.MinWorkers < 0 || config.MaxWorkers < 0 {
+		add("error", "min_workers/max_workers", "must not be negative")
+	} else if config.MaxWorkers > 0 && config.MinWorkers > config.MaxWorkers {
+		add("warning", "min_workers", "larger than max_workers; max_workers is raised to match")
+	}
+	if config.GitLog < 0 {
+		add("error", "git_log", "must not be negative")
+	}
+	if config.Format == "text" && !strings.Contains(config.FileHeader, "{path}") {
+		add("warning", "file_header", "without {path} the output cannot be restored")
+	}
+	if config.Save {
+		if dir := filepath.Dir(config.OutputFile); dir != "." {
+			if _, err := os.Stat(dir); err != nil {
+				add("error", "output_file", "directory %s does not exist", dir)
+			}
+		}
+	}
+
+	var aliases []string
+	for name := range config.Aliases {
+		aliases = append(aliases, name)
+	}
+	sort.Strings(aliases)
+	for _, name := range aliases {
+		field, value := "aliases."+name, config.Aliases[name]
+		if strings.HasPrefix(name, "-") || name == "" {
+			add("error", field, "alias names cannot be empty or start with -")
+		}
+		if _, err := splitArgs(value); err != nil {
+			add("error", field, "%v", err)
+		}
+	}
+
+	for i, spec := range config.Sinks {
+		field := fmt.Sprintf("sinks[%d]", i)
+		scheme, target, _ := strings.Cut(spec, ":")
+		if _, ok := sinks[scheme]; !ok {
+			continue
+		}
+		for _, name := range sinkEnv[scheme] {
+			if os.Getenv(name) == "" {
+				add("error", field, "%s is not set", name)
+			}
+		}
+		if (scheme == "slack" || scheme == "email") && strings.Trim(target, "; ") == "" {
+			add("error", field, "no %s target given", scheme)
+		}
+	}
+
+	return problems
+}
+
+// checkConnectivity test-connects the sinks that passed the static checks
+// and looks up models that are not in the registry.
+func checkConnectivity(config *Config) []configProblem {
+	var problems []configProblem
+
+	for i, spec := range config.Sinks {
+		scheme, target, _ := strings.Cut(spec, ":")
+		check, ok := sinkChecks[scheme]
+		if !ok {
+			continue
+		}
+		if err := check(target); err != nil {
+			problems = append(problems, configProblem{"error", fmt.Sprintf("sinks[%d]", i), fmt.Sprintf("%s: %v", scheme, err)})
+		}
+	}
+
+	if config.Model != "" {
+		if _, ok := modelRegistry[config.Model]; !ok {
+			if _, err := ollamaContextLength(ollamaEndpoint(), config.Model); err != nil {
+				problems = append(problems, configProblem{"error", "model", err.Error()})
+			}
+		}
+	}
+
+	return problems
+}
+
+func checkGoogleDrive(folderID string) error {
+	req, err := http.NewRequest("GET", "https://www.googleapis.com/drive/v3/about?fields=user", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("GOOGLE_DRIVE_TOKEN"))
+	return doSinkRequest(req, nil)
+}
+
+func checkOneDrive(folder string) error {
+	req, err := http.NewRequest("GET", "https://graph.microsoft.com/v1.0/me/drive", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("ONEDRIVE_TOKEN"))
+	return doSinkRequest(req, nil)
+}
+
+func checkSlack(channel string) error {
+	req, err := http.NewRequest("POST", slackAPI+"/auth.test", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("SLACK_BOT_TOKEN"))
+	_, err = doSlackRequest(req)
+	return err
+}
+
+// checkSMTP connects and authenticates without sending a message.
+func checkSMTP(recipients string) error {
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	client, err := dialSMTP(net.JoinHostPort(host, port), host, port == "465")
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		if err := client.Auth(smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)); err != nil {
+			return err
+		}
+	}
+	return client.Quit()
+}
@@ -0,0 +1,65 @@
+// config_validate_test.go
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckConfigFileReportsEveryUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.json")
+	data := `{"version": 1, "Format": "json", "formt": "aider", "ignore_dirz": ["vendor"], "include_ext": ["go"]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, problem := range checkConfigFile(path) {
+		if problem.Message == "unknown field, ignored" {
+			fields = append(fields, problem.Field)
+		}
+	}
+	if len(fields) != 3 || fields[0] != "formt" || fields[1] != "ignore_dirz" || fields[2] != "include_ext" {
+		t.Errorf("unknown fields reported: %v, want formt, ignore_dirz, include_ext", fields)
+	}
+}
+
+func TestValidateAgreesWithRuns(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_DEFAULTS", "none")
+	cases := []struct {
+		name  string
+		data  string
+		field string
+	}{
+		{name: "tail with delta", data: `{"save": true, "tail": true, "delta": true}`, field: "tail"},
+		{name: "tail without save", data: `{"tail": true}`, field: "tail"},
+		{name: "unknown sink", data: `{"sinks": ["nope:x"]}`, field: "sinks"},
+		{name: "valid", data: `{"format": "json"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profile.json")
+			if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := ParseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			runErr := ValidateConfig(config)
+			var fields []string
+			for _, problem := range checkConfig(config) {
+				if problem.Severity == "error" {
+					fields = append(fields, problem.Field)
+				}
+			}
+			if (runErr == nil) != (len(fields) == 0) {
+				t.Fatalf("a run returns %v but validate reports errors in %v", runErr, fields)
+			}
+			if tc.field != "" && (len(fields) != 1 || fields[0] != tc.field) {
+				t.Errorf("errors in %v, want %s", fields, tc.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// coverage.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fileCoverage is the coverage of one file from a Go cover profile or an
+// lcov tracefile.
+type fileCoverage struct {
+	// blocks holds Go profile blocks keyed by "start,end" position.
+	blocks map[string]coverBlock
+	// lines holds lcov line hit counts.
+	lines map[int]int
+	// funcs and funcLines hold lcov function hit counts and start lines.
+	funcs     map[string]int
+	funcLines map[string]int
+}
+
+type coverBlock struct {
+	startLine, endLine int
+	statements, count  int
+}
+
+// percent returns the share of covered statements (Go) or lines (lcov).
+func (c *fileCoverage) percent() (float64, bool) {
+	total, covered := 0, 0
+	for _, block := range c.blocks {
+		total += block.statements
+		if block.count > 0 {
+			covered += block.statements
+		}
+	}
+	for _, count := range c.lines {
+		total++
+		if count > 0 {
+			covered++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(covered) * 100 / float64(total), true
+}
+
+// loadCoverage reads a Go cover profile (go test -coverprofile) or an lcov
+// tracefile, detected from the first line.
+func loadCoverage(path string) (map[string]*fileCoverage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profile := make(map[string]*fileCoverage)
+	get := func(name string) *fileCoverage {
+		c, ok := profile[name]
+		if !ok {
+			c = &fileCoverage{blocks: map[string]coverBlock{}, lines: map[int]int{}, funcs: map[string]int{}, funcLines: map[string]int{}}
+			profile[name] = c
+		}
+		return c
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	var current *fileCoverage
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "mode:") || line == "end_of_record":
+		case strings.HasPrefix(line, "SF:"):
+			current = get(filepath.ToSlash(strings.TrimPrefix(line, "SF:")))
+		case strings.HasPrefix(line, "DA:") && current != nil:
+			fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(fields) >= 2 {
+				n, _ := strconv.Atoi(fields[0])
+				count, _ := strconv.Atoi(fields[1])
+				current.lines[n] += count
+			}
+		case strings.HasPrefix(line, "FN:") && current != nil:
+			if n, name, ok := strings.Cut(strings.TrimPrefix(line, "FN:"), ","); ok {
+				current.funcLines[name], _ = strconv.Atoi(n)
+			}
+		case strings.HasPrefix(line, "FNDA:") && current != nil:
+			if count, name, ok := strings.Cut(strings.TrimPrefix(line, "FNDA:"), ","); ok {
+				n, _ := strconv.Atoi(count)
+				current.funcs[name] += n
+			}
+		case strings.Contains(line, ".go:"):
+			name, key, block, err := parseCoverBlock(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			c := get(name)
+			// Merged profiles list a block once per test binary.
+			if existing, ok := c.blocks[key]; ok && existing.count > block.count {
+				block.count = existing.count
+			}
+			c.blocks[key] = block
+		}
+	}
+	return profile, scanner.Err()
+}
+
+// parseCoverBlock parses "file.go:12.2,14.16 3 1" into the file name, the
+// block position and the block.
+func parseCoverBlock(line string) (string, string, coverBlock, error) {
+	colon := strings.LastIndex(line, ".go:") + 3
+	fields := strings.Fields(line[colon+1:])
+	if len(fields) != 3 {
+		return "", "", coverBlock{}, fmt.Errorf("malformed cover profile line %q", line)
+	}
+	start, end, _ := strings.Cut(fields[0], ",")
+	startLine, _ := strconv.Atoi(strings.SplitN(start, ".", 2)[0])
+	endLine, _ := strconv.Atoi(strings.SplitN(end, ".", 2)[0])
+	statements, err1 := strconv.Atoi(fields[1])
+	count, err2 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil {
+		return "", "", coverBlock{}, fmt.Errorf("malformed cover profile line %q", line)
+	}
+	return line[:colon], fields[0], coverBlock{startLine, endLine, statements, count}, nil
+}
+
+// matchCoverage finds the profile entry for a result. Profiles name files
+// by import path or absolute path, so the entry sharing the longest run of
+// trailing path elements wins; ties are treated as no match.
+func matchCoverage(profile map[string]*fileCoverage, path string) *fileCoverage {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	var best *fileCoverage
+	bestScore, tie := 0, false
+	for name, c := range profile {
+		other := strings.Split(name, "/")
+		score := 0
+		for score < len(parts) && score < len(other) && parts[len(parts)-1-score] == other[len(other)-1-score] {
+			score++
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = c, score, false
+		case score == bestScore && score > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return best
+}
+
+// funcCoverage lists the functions of a result with their coverage, using
+// the statement blocks for Go files and the lcov function records
+// otherwise.
+func funcCoverage(result FileResult, c *fileCoverage) []string {
+	var lines []string
+	if len(c.funcs) > 0 {
+		var names []string
+		for name := range c.funcs {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return c.funcLines[names[i]] < c.funcLines[names[j]] })
+		for _, name := range names {
+			status := "covered"
+			if c.funcs[name] == 0 {
+				status = "not covered"
+			}
+			lines = append(lines, fmt.Sprintf("  %s: %s", name, status))
+		}
+		return lines
+	}
+
+	if len(c.blocks) == 0 || result.Language != "go" {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", result.Content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+		total, covered := 0, 0
+		for _, block := range c.blocks {
+			if block.startLine >= start && block.endLine <= end {
+				total += block.statements
+				if block.count > 0 {
+					covered += block.statements
+				}
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = receiverName(fn.Recv.List[0].Type) + "." + name
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %.1f%%", name, float64(covered)*100/float64(total)))
+	}
+	return lines
+}
+
+// coverageSection sets the Coverage of every result found in the profile
+// and lists the files and functions, least covered first.
+func coverageSection(results []FileResult, config *Config) (Section, error) {
+	profile, err := loadCoverage(config.Coverage)
+	if err != nil {
+		return Section{}, fmt.Errorf("reading coverage: %w", err)
+	}
+
+	type entry struct {
+		path    string
+		percent float64
+		funcs   []string
+	}
+	var entries []entry
+	for i := range results {
+		c := matchCoverage(profile, results[i].Path)
+		if c == nil {
+			continue
+		}
+		percent, ok := c.percent()
+		if !ok {
+			continue
+		}
+		percent = math.Round(percent*10) / 10
+		results[i].Coverage = &percent
+		entries = append(entries, entry{filepath.ToSlash(results[i].Path), percent, funcCoverage(results[i], c)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].percent < entries[j].percent })
+
+	var buffer strings.Builder
+	if len(entries) == 0 {
+		buffer.WriteString("No included file appears in " + config.Coverage + ".\n")
+	}
+	for _, e := range entries {
+		buffer.WriteString(fmt.Sprintf("%s: %.1f%%\n", e.path, e.percent))
+		for _, line := range e.funcs {
+			buffer.WriteString(line + "\n")
+		}
+	}
+	return Section{Title: "Test coverage", Content: buffer.String()}, nil
+}
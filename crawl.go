@@ -0,0 +1,325 @@
+// crawl.go
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type githubRepo struct {
+	Name     string   `json:"name"`
+	FullName string   `json:"full_name"`
+	CloneURL string   `json:"clone_url"`
+	Language string   `json:"language"`
+	Topics   []string `json:"topics"`
+	Archived bool     `json:"archived"`
+	Fork     bool     `json:"fork"`
+	Size     int64    `json:"size"` // KiB, as reported by the API
+}
+
+// crawlState is persisted after every repository so an interrupted crawl
+// can resume where it stopped.
+type crawlState struct {
+	Done []string `json:"done"`
+}
+
+var githubClient = &http.Client{Transport: networkTransport}
+
+// crawlSleep waits out -delay and GitHub rate limits; tests replace it.
+var crawlSleep = time.Sleep
+
+func githubAPI() string {
+	// GITHUB_API_URL is also how GitHub Enterprise and Actions expose the API.
+	if api := os.Getenv("GITHUB_API_URL"); api != "" {
+		return strings.TrimRight(api, "/")
+	}
+	return "https://api.github.com"
+}
+
+func runCrawl(args []string) error {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	orgFlag := fs.String("org", "", "GitHub organization to crawl")
+	topicFlag := fs.String("topic", "", "Comma-separated list of topics; repositories need at least one of them")
+	languageFlag := fs.String("language", "", "Comma-separated list of primary languages to include")
+	includeArchivedFlag := fs.Bool("include-archived", false, "Include archived repositories")
+	includeForksFlag := fs.Bool("include-forks", false, "Include forked repositories")
+	profileFlag := fs.String("profile", "", "Config file applied to every repository")
+	outDirFlag := fs.String("out-dir", "crawl", "Directory for the per-repository outputs")
+	stateFlag := fs.String("state", "", "State file used to resume an interrupted crawl (default: <out-dir>/crawl-state.json)")
+	delayFlag := fs.Duration("delay", time.Second, "Pause between repositories to stay below API and clone rate limits")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *orgFlag == "" {
+		return errors.New("-org is required")
+	}
+
+	if err := os.MkdirAll(*outDirFlag, 0755); err != nil {
+		return err
+	}
+	statePath := *stateFlag
+	if statePath == "" {
+		statePath = filepath.Join(*outDirFlag, "crawl-state.json")
+	}
+	state, err := loadCrawlState(statePath)
+	if err != nil {
+		return err
+	}
+
+	repos, err := listOrgRepos(*orgFlag)
+	if err != nil {
+		return err
+	}
+	repos = filterRepos(repos, parseCommaSeparated(*topicFlag), parseCommaSeparated(*languageFlag), *includeArchivedFlag, *includeForksFlag)
+	fmt.Printf("Found %d matching repositories in %s\n", len(repos), *orgFlag)
+
+	done := make(map[string]bool)
+	for _, name := range state.Done {
+		done[name] = true
+	}
+
+	runID := newRunID()
+	var outcomes []batchOutcome
+	for i, repo := range repos {
+		if done[repo.Name] {
+			fmt.Printf("[%d/%d] %s: already done, skipping\n", i+1, len(repos), repo.Name)
+			continue
+		}
+		fmt.Printf("[%d/%d] %s\n", i+1, len(repos), repo.Name)
+
+		outcome := crawlRepo(repo, *profileFlag, *outDirFlag, runID)
+		outcomes = append(outcomes, outcome)
+		if outcome.Err == nil {
+			state.Done = append(state.Done, repo.Name)
+			if err := saveCrawlState(statePath, state); err != nil {
+				return err
+			}
+		}
+		crawlSleep(*delayFlag)
+	}
+
+	summary := formatBatchSummary(runID, outcomes)
+	fmt.Print(summary)
+	if err := SaveOutput(summary, filepath.Join(*outDirFlag, "summary.txt")); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed; re-run to retry them", failed, len(outcomes))
+	}
+	return nil
+}
+
+func crawlRepo(repo githubRepo, profile, outDir, runID string) batchOutcome {
+	cloneDir, err := makeTempDir("clone-")
+	if err != nil {
+		return batchOutcome{Entry: BatchEntry{Name: repo.Name}, Err: err}
+	}
+	defer removeTempDir(cloneDir)
+
+	// The API size covers the packed history; the checkout needs about as
+	// much again.
+	if err := ensureDiskSpace(cloneDir, 2*repo.Size<<10); err != nil {
+		return batchOutcome{Entry: BatchEntry{Name: repo.Name}, Err: err}
+	}
+
+	if err := shallowClone(repo.CloneURL, cloneDir); err != nil {
+		return batchOutcome{Entry: BatchEntry{Name: repo.Name}, Err: err}
+	}
+
+	ref := "HEAD"
+	if out, err := exec.Command("git", "-C", cloneDir, "rev-parse", "--short", "HEAD").Output(); err == nil {
+		ref = strings.TrimSpace(string(out))
+	}
+
+	return processBatchEntry(BatchEntry{
+		Name:    repo.Name,
+		Dirs:    []string{cloneDir},
+		Profile: profile,
+		Source:  fmt.Sprintf("git:%s@%s", repo.FullName, ref),
+	}, outDir, runID)
+}
+
+func shallowClone(cloneURL, dir string) error {
+	return retry("git", func() error {
+		// A failed attempt may leave a partial checkout behind.
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		requestLimiter("git").wait(1)
+		cmd := gitCommand("clone", "--quiet", "--depth", "1", cloneURL, dir)
+		cmd.Env = append(cmd.Env, gitAuthEnv(cloneURL)...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			err = fmt.Errorf("cloning %s: %v: %s", cloneURL, err, strings.TrimSpace(string(output)))
+			if permanentCloneFailure(string(output)) {
+				return err
+			}
+			return retryable(err)
+		}
+		return nil
+	})
+}
+
+// gitAuthEnv passes $GITHUB_TOKEN to git as an HTTP header for the host of
+// cloneURL. Going through the environment keeps the token out of the
+// command line (ps, /proc/*/cmdline) and out of the clone's .git/config.
+func gitAuthEnv(cloneURL string) []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	token := os.Getenv("GITHUB_TOKEN")
+	u, err := url.Parse(cloneURL)
+	if token == "" || err != nil || u.Host == "" {
+		return env
+	}
+	// Entries already configured through the environment are kept.
+	index, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return append(env,
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", index+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", index, u.Scheme, u.Host),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", index, credentials),
+	)
+}
+
+// permanentCloneFailure reports git errors that retrying cannot fix: a
+// rejected token or a repository that does not exist or is not visible.
+func permanentCloneFailure(output string) bool {
+	output = strings.ToLower(output)
+	for _, marker := range []string{"authentication failed", "repository not found", "could not read username", "terminal prompts disabled", "returned error: 401", "returned error: 403", "returned error: 404"} {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func listOrgRepos(org string) ([]githubRepo, error) {
+	var repos []githubRepo
+
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("%s/orgs/%s/repos?per_page=100&page=%d", githubAPI(), url.PathEscape(org), page)
+		var batch []githubRepo
+		if err := githubGet(endpoint, &batch); err != nil {
+			return nil, err
+		}
+		repos = append(repos, batch...)
+		if len(batch) < 100 {
+			return repos, nil
+		}
+	}
+}
+
+// githubGet performs an authenticated API request, waiting for the rate
+// limit window to reset when it has been exhausted.
+func githubGet(endpoint string, out interface{}) error {
+	for {
+		req, err := http.NewRequest("GET", endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := doHTTP(githubClient, req, "github")
+		if err != nil {
+			return err
+		}
+
+		if (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			resp.Body.Close()
+			reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			wait := time.Until(time.Unix(reset, 0)) + time.Second
+			if wait < time.Second {
+				wait = time.Minute
+			}
+			fmt.Printf("GitHub rate limit reached, waiting %s\n", formatDuration(wait))
+			crawlSleep(wait)
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: unexpected status %s", endpoint, resp.Status)
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
+
+func filterRepos(repos []githubRepo, topics, languages []string, includeArchived, includeForks bool) []githubRepo {
+	var filtered []githubRepo
+
+	for _, repo := range repos {
+		if repo.Archived && !includeArchived || repo.Fork && !includeForks {
+			continue
+		}
+		if len(languages) > 0 && !containsFold(languages, repo.Language) {
+			continue
+		}
+		if len(topics) > 0 {
+			matched := false
+			for _, topic := range repo.Topics {
+				if containsFold(topics, topic) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		filtered = append(filtered, repo)
+	}
+
+	return filtered
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadCrawlState(path string) (*crawlState, error) {
+	state := &crawlState{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parsing crawl state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveCrawlState(path string, state *crawlState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
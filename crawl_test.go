@@ -0,0 +1,235 @@
+// crawl_test.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordCrawlSleeps replaces crawlSleep for the duration of a test.
+func recordCrawlSleeps(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	t.Cleanup(func() { crawlSleep = time.Sleep })
+	crawlSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return &sleeps
+}
+
+// fakeGitHub serves /orgs/<org>/repos from repos, 100 per page, and
+// records the pages requested.
+func fakeGitHub(t *testing.T, repos []githubRepo) *[]string {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/acme/repos" || r.URL.Query().Get("per_page") != "100" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		pages = append(pages, r.URL.Query().Get("page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start, end := (page-1)*100, page*100
+		if start > len(repos) {
+			start = len(repos)
+		}
+		if end > len(repos) {
+			end = len(repos)
+		}
+		json.NewEncoder(w).Encode(repos[start:end])
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GITHUB_API_URL", server.URL+"/")
+	t.Setenv("GITHUB_TOKEN", "secret")
+	return &pages
+}
+
+func TestListOrgReposPages(t *testing.T) {
+	cases := []struct {
+		repos int
+		pages string
+	}{
+		{0, "1"},
+		{3, "1"},
+		{100, "1,2"},
+		{250, "1,2,3"},
+	}
+	for _, tc := range cases {
+		t.Run(strconv.Itoa(tc.repos), func(t *testing.T) {
+			repos := make([]githubRepo, tc.repos)
+			for i := range repos {
+				repos[i].Name = fmt.Sprintf("repo%d", i)
+			}
+			pages := fakeGitHub(t, repos)
+
+			got, err := listOrgRepos("acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tc.repos {
+				t.Errorf("listed %d repositories, want %d", len(got), tc.repos)
+			}
+			if strings.Join(*pages, ",") != tc.pages {
+				t.Errorf("requested pages %v, want %s", *pages, tc.pages)
+			}
+		})
+	}
+}
+
+func TestGithubGetWaitsForRateLimit(t *testing.T) {
+	sleeps := recordCrawlSleeps(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"name": "repo"}`))
+	}))
+	defer server.Close()
+
+	var repo githubRepo
+	if err := githubGet(server.URL, &repo); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || repo.Name != "repo" {
+		t.Errorf("got %d calls and %+v, want 2 calls and the repository", calls, repo)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] < 59*time.Minute || (*sleeps)[0] > time.Hour+time.Second {
+		t.Errorf("slept %v, want about an hour until the reset", *sleeps)
+	}
+}
+
+func TestGitAuthEnvIsScopedToCloneHost(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	t.Setenv("GITHUB_TOKEN", "secret")
+	env := strings.Join(gitAuthEnv("https://github.example.com/acme/repo.git"), "\n")
+	if !strings.Contains(env, "GIT_CONFIG_KEY_0=http.https://github.example.com/.extraHeader") {
+		t.Errorf("header not scoped to the clone host:\n%s", env)
+	}
+	if strings.Contains(env, "secret") {
+		t.Errorf("token passed in clear text:\n%s", env)
+	}
+
+	t.Setenv("GIT_CONFIG_COUNT", "2")
+	if env := strings.Join(gitAuthEnv("https://github.example.com/acme/repo.git"), "\n"); !strings.Contains(env, "GIT_CONFIG_COUNT=3") || !strings.Contains(env, "GIT_CONFIG_KEY_2=") {
+		t.Errorf("existing config entries overwritten:\n%s", env)
+	}
+
+	for _, cloneURL := range []string{"/srv/git/repo.git", "git@github.com:acme/repo.git"} {
+		if env := gitAuthEnv(cloneURL); len(env) != 1 {
+			t.Errorf("gitAuthEnv(%s) = %v, want no header without a host", cloneURL, env)
+		}
+	}
+	t.Setenv("GITHUB_TOKEN", "")
+	if env := gitAuthEnv("https://github.example.com/acme/repo.git"); len(env) != 1 {
+		t.Errorf("header set without a token: %v", env)
+	}
+}
+
+func TestFilterRepos(t *testing.T) {
+	repos := []githubRepo{
+		{Name: "api", Language: "Go", Topics: []string{"backend"}},
+		{Name: "web", Language: "TypeScript", Topics: []string{"frontend"}},
+		{Name: "old", Language: "Go", Archived: true},
+		{Name: "copy", Language: "Go", Fork: true},
+	}
+	cases := []struct {
+		name              string
+		topics, languages []string
+		archived, forks   bool
+		want              string
+	}{
+		{name: "defaults", want: "api,web"},
+		{name: "language", languages: []string{"go"}, want: "api"},
+		{name: "topic", topics: []string{"FRONTEND", "docs"}, want: "web"},
+		{name: "archived and forks", languages: []string{"Go"}, archived: true, forks: true, want: "api,old,copy"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			for _, repo := range filterRepos(repos, tc.topics, tc.languages, tc.archived, tc.forks) {
+				names = append(names, repo.Name)
+			}
+			if got := strings.Join(names, ","); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunCrawlResumes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("CODEXGIGANTUS_DEFAULTS", "none")
+	t.Setenv("CODEXGIGANTUS_HISTORY", filepath.Join(dir, "history.jsonl"))
+	t.Setenv("CODEXGIGANTUS_TMPDIR", filepath.Join(dir, "tmp"))
+	sleeps := recordCrawlSleeps(t)
+
+	origin := filepath.Join(dir, "origin")
+	if err := os.MkdirAll(origin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(origin, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "main.go"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", origin}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	pages := fakeGitHub(t, []githubRepo{
+		{Name: "app", FullName: "acme/app", CloneURL: origin},
+		{Name: "old", FullName: "acme/old", CloneURL: origin, Archived: true},
+	})
+
+	outDir := filepath.Join(dir, "out")
+	if err := runCrawl([]string{"-org", "acme", "-out-dir", outDir, "-delay", "2s"}); err != nil {
+		t.Fatal(err)
+	}
+	output, err := os.ReadFile(filepath.Join(outDir, "app.txt"))
+	if err != nil || !strings.Contains(string(output), "package main") {
+		t.Errorf("app.txt = %q, %v", output, err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("archived repository crawled: %v", err)
+	}
+	state, err := loadCrawlState(filepath.Join(outDir, "crawl-state.json"))
+	if err != nil || strings.Join(state.Done, ",") != "app" {
+		t.Errorf("state %+v, %v; want app done", state, err)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 2*time.Second {
+		t.Errorf("slept %v, want one -delay", *sleeps)
+	}
+
+	// A second run skips what the state lists as done.
+	if err := os.Remove(filepath.Join(outDir, "app.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCrawl([]string{"-org", "acme", "-out-dir", outDir, "-delay", "2s"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "app.txt")); !os.IsNotExist(err) {
+		t.Errorf("done repository crawled again: %v", err)
+	}
+	if len(*pages) != 2 || len(*sleeps) != 1 {
+		t.Errorf("got pages %v and sleeps %v after the second run", *pages, *sleeps)
+	}
+}
@@ -0,0 +1,393 @@
+// datafmt.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	yamlMappingKeyRe  = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|'[^']*'|[^\s#'"{\[][^#]*?)\s*:(?:\s|$)`)
+	yamlBlockScalarRe = regexp.MustCompile(`(?:^|[:\-]\s+|^-)[|>][-+0-9]*\s*(?:#.*)?$`)
+	yamlAnchorRe      = regexp.MustCompile(`(?:^|[\s:\-\[,])&[^\s,\]}]+`)
+	tomlKeyRe         = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|'[^']*'|[A-Za-z0-9_.\-" ]+?)\s*=`)
+	tomlHeaderRe      = regexp.MustCompile(`^\s*(\[\[?)\s*([^\]]+?)\s*\]\]?\s*(?:#.*)?$`)
+)
+
+// normalizeData rewrites JSON, YAML and TOML files in a stable form: keys
+// sorted, insignificant whitespace removed and, with maxItems above 0,
+// arrays cut to their first maxItems entries with a note of how many were
+// left out. Files that do not parse, and other files, are returned as they
+// are.
+func normalizeData(content, path string, maxItems int) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return normalizeJSON(content, maxItems)
+	case ".yaml", ".yml":
+		return normalizeYAML(content, maxItems)
+	case ".toml":
+		return normalizeTOML(content, maxItems)
+	}
+	return content
+}
+
+func normalizeJSON(content string, maxItems int) string {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return content
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return content
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	// Maps are encoded with their keys sorted.
+	if err := encoder.Encode(truncateJSON(value, maxItems)); err != nil {
+		return content
+	}
+	return buffer.String()
+}
+
+func truncateJSON(value any, maxItems int) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = truncateJSON(item, maxItems)
+		}
+	case []any:
+		if maxItems > 0 && len(v) > maxItems {
+			v = append(v[:maxItems:maxItems], fmt.Sprintf("... %d more items", len(v)-maxItems))
+		}
+		for i := range v {
+			v[i] = truncateJSON(v[i], maxItems)
+		}
+		return v
+	}
+	return value
+}
+
+// yamlBlock is a line of a YAML document with the comments before it and
+// the more indented lines that belong to it.
+type yamlBlock struct {
+	head []string
+	body []string
+}
+
+func (b *yamlBlock) line() string {
+	return b.head[len(b.head)-1]
+}
+
+// normalizeYAML works on the lines of each document: blank lines outside
+// block scalars are dropped, the keys of every mapping are sorted and long
+// block sequences are cut. Comments move with the line after them.
+// Documents with anchors are only trimmed, as sorting could put an alias
+// before its anchor.
+func normalizeYAML(content string, maxItems int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	var out, doc []string
+	flush := func() {
+		if yamlAnchorRe.MatchString(strings.Join(doc, "\n")) {
+			for _, line := range doc {
+				if line = strings.TrimRight(line, " \t"); line != "" {
+					out = append(out, line)
+				}
+			}
+		} else if normalized, ok := normalizeYAMLLines(doc, maxItems); ok {
+			out = append(out, normalized...)
+		} else {
+			out = append(out, doc...)
+		}
+		doc = nil
+	}
+	for _, line := range lines {
+		if line == "---" || strings.HasPrefix(line, "--- ") || line == "..." {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		doc = append(doc, line)
+	}
+	flush()
+	return strings.Join(out, "\n") + "\n"
+}
+
+// normalizeYAMLLines normalizes sibling blocks and, recursively, their
+// bodies. It reports false when the lines are not indented consistently.
+func normalizeYAMLLines(lines []string, maxItems int) ([]string, bool) {
+	var blocks []*yamlBlock
+	// header holds the comments set apart from the first line by a blank
+	// line; they stay on top.
+	var header, pending []string
+	indent := -1
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimSpace(line)
+		depth := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case trimmed == "" && len(blocks) == 0:
+			header, pending = append(header, pending...), nil
+		case trimmed == "" || (strings.HasPrefix(trimmed, "#") && (indent < 0 || depth <= indent)):
+			if len(blocks) > 0 && (trimmed == "" || depth > indent) {
+				blocks[len(blocks)-1].body = append(blocks[len(blocks)-1].body, line)
+			} else if trimmed != "" {
+				pending = append(pending, line)
+			}
+		case depth == indent && len(blocks) > 0 && isYAMLItem(trimmed) && !isYAMLItem(strings.TrimSpace(blocks[0].line())):
+			// An indentless sequence (key:\n- item) belongs to the key
+			// before it.
+			last := blocks[len(blocks)-1]
+			last.body = append(last.body, append(pending, line)...)
+			pending = nil
+		case indent < 0 || depth == indent:
+			indent = depth
+			blocks = append(blocks, &yamlBlock{head: append(pending, line)})
+			pending = nil
+		case depth > indent && len(blocks) > 0:
+			blocks[len(blocks)-1].body = append(blocks[len(blocks)-1].body, line)
+		default:
+			return nil, false
+		}
+	}
+
+	for _, block := range blocks {
+		if yamlBlockScalarRe.MatchString(block.line()) || opensFlow(block.line()) {
+			// Literal text or a flow collection: keep the lines.
+			for len(block.body) > 0 && block.body[len(block.body)-1] == "" {
+				block.body = block.body[:len(block.body)-1]
+			}
+			continue
+		}
+		body, ok := normalizeYAMLLines(block.body, maxItems)
+		if !ok {
+			return nil, false
+		}
+		block.body = body
+	}
+
+	sequence, keys := true, make([]string, len(blocks))
+	for i, block := range blocks {
+		item := strings.TrimSpace(block.line())
+		sequence = sequence && isYAMLItem(item)
+		if match := yamlMappingKeyRe.FindStringSubmatch(block.line()); match != nil && match[1] != "<<" && !strings.HasPrefix(item, "- ") {
+			keys[i] = strings.Trim(match[1], `"'`)
+		} else {
+			keys = nil
+		}
+		if keys == nil && !sequence {
+			break
+		}
+	}
+	switch {
+	case sequence && maxItems > 0 && len(blocks) > maxItems:
+		more := fmt.Sprintf("%s# ... %d more items", strings.Repeat(" ", indent), len(blocks)-maxItems)
+		blocks = append(blocks[:maxItems:maxItems], &yamlBlock{head: []string{more}})
+	case !sequence && keys != nil:
+		order := make(map[*yamlBlock]string, len(blocks))
+		for i, block := range blocks {
+			order[block] = keys[i]
+		}
+		sort.SliceStable(blocks, func(i, j int) bool { return order[blocks[i]] < order[blocks[j]] })
+	}
+
+	out := header
+	for _, block := range blocks {
+		out = append(out, block.head...)
+		out = append(out, block.body...)
+	}
+	return append(out, pending...), true
+}
+
+// isYAMLItem reports whether a trimmed YAML line starts a sequence item.
+func isYAMLItem(trimmed string) bool {
+	return trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+}
+
+// opensFlow reports whether a YAML line starts a flow collection that
+// continues on the next lines.
+func opensFlow(line string) bool {
+	if comment := strings.Index(line, " #"); comment >= 0 {
+		line = line[:comment]
+	}
+	return strings.Count(line, "[")+strings.Count(line, "{") > strings.Count(line, "]")+strings.Count(line, "}")
+}
+
+// tomlEntry is a key/value pair with the comments before it and the lines
+// of a value that spans several.
+type tomlEntry struct {
+	key   string
+	lines []string
+}
+
+// tomlTable is a [table] or [[array]] header and its entries; the root
+// table has no header.
+type tomlTable struct {
+	header  []string
+	name    string
+	array   bool
+	entries []*tomlEntry
+}
+
+// normalizeTOML sorts the keys of every table (tables keep their order),
+// drops blank lines except before table headers, and cuts [[array]] tables
+// and multi-line arrays to maxItems entries.
+func normalizeTOML(content string, maxItems int) string {
+	root := &tomlTable{}
+	tables := []*tomlTable{root}
+	var header, pending []string
+	var open *tomlEntry
+	var state tomlScan
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if open != nil {
+			open.lines = append(open.lines, line)
+			if state.scan(line) {
+				open = nil
+			}
+			continue
+		}
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimSpace(line)
+		if match := tomlHeaderRe.FindStringSubmatch(line); match != nil {
+			tables = append(tables, &tomlTable{header: append(pending, trimmed), name: match[2], array: match[1] == "[["})
+			pending = nil
+			continue
+		}
+		switch {
+		case trimmed == "" && len(tables) == 1 && len(root.entries) == 0:
+			// Comments set apart from the first key stay on top.
+			header, pending = append(header, pending...), nil
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, trimmed)
+		default:
+			match := tomlKeyRe.FindStringSubmatch(line)
+			if match == nil {
+				return content
+			}
+			entry := &tomlEntry{key: strings.TrimSpace(match[1]), lines: append(pending, trimmed)}
+			pending = nil
+			current := tables[len(tables)-1]
+			current.entries = append(current.entries, entry)
+			state = tomlScan{}
+			if !state.scan(line[len(match[0]):]) {
+				open = entry
+			}
+		}
+	}
+	if open != nil {
+		return content
+	}
+
+	out := header
+	counts := make(map[string]int)
+	skipped := ""
+	for i, table := range tables {
+		if table.array {
+			counts[table.name]++
+		}
+		// Sub-tables of a dropped [[array]] element are dropped with it.
+		if skipped != "" && strings.HasPrefix(table.name, skipped+".") {
+			continue
+		}
+		skipped = ""
+		if table.array && maxItems > 0 && counts[table.name] > maxItems {
+			skipped = table.name
+			if counts[table.name] == maxItems+1 {
+				total := 0
+				for _, t := range tables[i:] {
+					if t.array && t.name == table.name {
+						total++
+					}
+				}
+				out = append(out, fmt.Sprintf("# ... %d more [[%s]] tables", total, table.name))
+			}
+			continue
+		}
+
+		if len(table.header) > 0 {
+			if len(out) > 0 {
+				out = append(out, "")
+			}
+			out = append(out, table.header...)
+		}
+		sort.SliceStable(table.entries, func(i, j int) bool { return table.entries[i].key < table.entries[j].key })
+		for _, entry := range table.entries {
+			out = append(out, truncateTOMLArray(entry.lines, maxItems)...)
+		}
+	}
+	out = append(out, pending...)
+	return strings.Join(out, "\n") + "\n"
+}
+
+// truncateTOMLArray cuts a multi-line array written one item per line.
+func truncateTOMLArray(lines []string, maxItems int) []string {
+	first := 0
+	for first < len(lines) && strings.HasPrefix(lines[first], "#") {
+		first++
+	}
+	items := lines[first+1:]
+	if maxItems <= 0 || len(items) <= maxItems+1 || !strings.HasSuffix(strings.TrimSpace(lines[first]), "[") {
+		return lines
+	}
+	if strings.TrimSpace(items[len(items)-1]) != "]" {
+		return lines
+	}
+	items = items[:len(items)-1]
+	indent := items[0][:len(items[0])-len(strings.TrimLeft(items[0], " \t"))]
+	out := append([]string(nil), lines[:first+1+maxItems]...)
+	out = append(out, fmt.Sprintf("%s# ... %d more items", indent, len(items)-maxItems), "]")
+	return out
+}
+
+// tomlScan follows the brackets and multi-line strings of a TOML value
+// across lines.
+type tomlScan struct {
+	depth     int
+	multiline string
+}
+
+// scan reads one line of a value and reports whether the value is
+// complete at its end.
+func (s *tomlScan) scan(line string) bool {
+	for i := 0; i < len(line); i++ {
+		rest := line[i:]
+		if s.multiline != "" {
+			if strings.HasPrefix(rest, s.multiline) {
+				i += len(s.multiline) - 1
+				s.multiline = ""
+			} else if s.multiline == `"""` && rest[0] == '\\' {
+				i++
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"):
+			s.multiline = rest[:3]
+			i += 2
+		case rest[0] == '"' || rest[0] == '\'':
+			quote := rest[0]
+			for i++; i < len(line) && line[i] != quote; i++ {
+				if quote == '"' && line[i] == '\\' {
+					i++
+				}
+			}
+		case rest[0] == '[' || rest[0] == '{':
+			s.depth++
+		case rest[0] == ']' || rest[0] == '}':
+			s.depth--
+		case rest[0] == '#':
+			return s.depth <= 0
+		}
+	}
+	return s.depth <= 0 && s.multiline == ""
+}
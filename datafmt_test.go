@@ -0,0 +1,100 @@
+// datafmt_test.go
+package main
+
+import "testing"
+
+func TestNormalizeYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		in, want string
+		maxItems int
+	}{
+		{
+			name: "sorted keys",
+			in:   "b: 1\n\na: 2\n",
+			want: "a: 2\nb: 1\n",
+		},
+		{
+			name: "anchors only trimmed",
+			in:   "b: &base\n  x: 1\n\na:\n  <<: *base\n",
+			want: "b: &base\n  x: 1\na:\n  <<: *base\n",
+		},
+		{
+			name: "block scalar kept",
+			in:   "b: |\n  z line\n\n  a line\na: >-\n  folded\n",
+			want: "a: >-\n  folded\nb: |\n  z line\n\n  a line\n",
+		},
+		{
+			name: "indentless list stays with its key",
+			in:   "z: 1\nitems:\n- b\n- a\nname: x\n",
+			want: "items:\n- b\n- a\nname: x\nz: 1\n",
+		},
+		{
+			name:     "indentless list cut",
+			in:       "items:\n- a\n- b\n- c\nz: 1\n",
+			want:     "items:\n- a\n- b\n# ... 1 more items\nz: 1\n",
+			maxItems: 2,
+		},
+		{
+			name: "indentless list of mappings",
+			in:   "env:\n- name: B\n  value: 2\n- name: A\n  value: 1\nan: 0\n",
+			want: "an: 0\nenv:\n- name: B\n  value: 2\n- name: A\n  value: 1\n",
+		},
+		{
+			name: "documents kept apart",
+			in:   "b: 1\na: 2\n---\nd: 3\nc: 4\n",
+			want: "a: 2\nb: 1\n---\nc: 4\nd: 3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeData(tt.in, "x.yaml", tt.maxItems); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		in, want string
+		maxItems int
+	}{
+		{
+			name: "sorted keys, tables in order",
+			in:   "b = 1\na = 2\n\n[z]\ny = 1\nx = 2\n\n[a]\nk = 1\n",
+			want: "a = 2\nb = 1\n\n[z]\nx = 2\ny = 1\n\n[a]\nk = 1\n",
+		},
+		{
+			name:     "array tables cut",
+			in:       "[[p]]\nn = 1\n[[p]]\nn = 2\n[[p]]\nn = 3\n[[p]]\nn = 4\n",
+			want:     "[[p]]\nn = 1\n\n[[p]]\nn = 2\n# ... 2 more [[p]] tables\n",
+			maxItems: 2,
+		},
+		{
+			name:     "sub-tables dropped with their element",
+			in:       "[[p]]\nn = 1\n[p.opts]\nx = 1\n[[p]]\nn = 2\n[p.opts]\nx = 2\n[q]\ny = 1\n",
+			want:     "[[p]]\nn = 1\n\n[p.opts]\nx = 1\n# ... 1 more [[p]] tables\n\n[q]\ny = 1\n",
+			maxItems: 1,
+		},
+		{
+			name:     "multi-line array cut",
+			in:       "list = [\n  1,\n  2,\n  3,\n]\n",
+			want:     "list = [\n  1,\n  2,\n  # ... 1 more items\n]\n",
+			maxItems: 2,
+		},
+		{
+			name: "multi-line string kept",
+			in:   "b = \"\"\"\nz = 1\n\"\"\"\na = 1\n",
+			want: "a = 1\nb = \"\"\"\nz = 1\n\"\"\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeData(tt.in, "x.toml", tt.maxItems); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
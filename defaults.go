@@ -0,0 +1,114 @@
+// defaults.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// defaultsPath returns the per-user defaults file: $CODEXGIGANTUS_DEFAULTS,
+// or codexgigantus/defaults.json in the user config directory
+// (~/.config on Linux). "none" disables it.
+func defaultsPath() string {
+	if path := os.Getenv("CODEXGIGANTUS_DEFAULTS"); path != "" {
+		if path == "none" {
+			return ""
+		}
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "codexgigantus", "defaults.json")
+}
+
+// userDefaults returns the built-in defaults with the per-user defaults
+// file applied on top. Project config files and flags are applied over
+// the result, so personal preferences never override a project's choices.
+// The returned config remembers the file's settings in personal, so
+// SaveConfigFile can leave them out of the profiles it writes.
+func userDefaults() (*Config, error) {
+	config := defaultConfig()
+	path := defaultsPath()
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading defaults %s: %w", path, err)
+	}
+	if err = checkConfigVersion(data); err == nil {
+		err = json.Unmarshal(data, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing defaults %s: %w", path, err)
+	}
+	personal := *config
+	config.personal = &personal
+	return config, nil
+}
+
+// overrideUserDefaults forgets the per-user defaults that the project
+// config file now loaded into config sets itself: the JSON keys in fields,
+// and any setting the file changed.
+func overrideUserDefaults(config *Config, fields map[string]json.RawMessage) {
+	if config.personal == nil {
+		return
+	}
+	builtin := reflect.ValueOf(defaultConfig()).Elem()
+	personal := reflect.ValueOf(config.personal).Elem()
+	current := reflect.ValueOf(config).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		name, ok := configFieldName(current.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if _, set := fields[name]; set || !reflect.DeepEqual(current.Field(i).Interface(), personal.Field(i).Interface()) {
+			personal.Field(i).Set(builtin.Field(i))
+		}
+	}
+}
+
+// stripUserDefaults resets the settings of config that still hold a value
+// taken from the per-user defaults file to the built-in default, so a
+// saved profile carries no personal preferences. A flag that repeats the
+// personal default is indistinguishable from it and is dropped as well.
+func stripUserDefaults(config *Config) {
+	if config.personal == nil {
+		return
+	}
+	builtin := reflect.ValueOf(defaultConfig()).Elem()
+	personal := reflect.ValueOf(config.personal).Elem()
+	current := reflect.ValueOf(config).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		if _, ok := configFieldName(current.Type().Field(i)); !ok {
+			continue
+		}
+		value := personal.Field(i).Interface()
+		if !reflect.DeepEqual(value, builtin.Field(i).Interface()) && reflect.DeepEqual(current.Field(i).Interface(), value) {
+			current.Field(i).Set(builtin.Field(i))
+		}
+	}
+	config.personal = nil
+}
+
+// configFieldName returns the JSON key of a Config field that config files
+// hold.
+func configFieldName(field reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if !field.IsExported() || name == "" || name == "-" {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,159 @@
+// delta.go
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDeltaListed caps the unchanged and removed paths listed by --delta.
+const maxDeltaListed = 100
+
+// RunManifest records the files a run included and their hashes, so the
+// next --delta run can leave out what the model has already seen.
+type RunManifest struct {
+	RunID string    `json:"run_id"`
+	Time  time.Time `json:"time"`
+	// Files maps each included path to the hash of its content.
+	Files map[string]string `json:"files"`
+}
+
+// manifestPath returns config.Manifest, or a file named after the profile,
+// the directories and the filters that pick the files, under
+// $CODEXGIGANTUS_MANIFESTS or the user config dir. Changing a filter
+// starts a new manifest instead of reporting the files it adds or drops
+// as changed or removed.
+func manifestPath(config *Config) string {
+	if config.Manifest != "" {
+		return config.Manifest
+	}
+	dir := os.Getenv("CODEXGIGANTUS_MANIFESTS")
+	if dir == "" {
+		base, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(base, "codexgigantus", "manifests")
+	}
+	key := config.Profile
+	for _, d := range config.Dirs {
+		if abs, err := filepath.Abs(d); err == nil {
+			d = abs
+		}
+		key += "\x00" + d
+	}
+	for _, filter := range [][]string{config.IncludeExts, config.IgnoreExts, config.IgnoreFiles, config.IgnoreDirs, config.OnlyClasses, config.IncludeDeps, config.IncludeStd} {
+		sorted := append([]string(nil), filter...)
+		sort.Strings(sorted)
+		key += "\x01" + strings.Join(sorted, "\x00")
+	}
+	key += fmt.Sprintf("\x01%s/%s\x01%t", config.GOOS, config.GOARCH, config.Recursive)
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+func loadManifest(path string) (*RunManifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := &RunManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// saveManifest records results as the files of this run.
+func saveManifest(path string, results []FileResult, config *Config) error {
+	if path == "" {
+		return errors.New("no user config directory")
+	}
+	manifest := RunManifest{RunID: config.RunID, Time: time.Now().UTC(), Files: make(map[string]string, len(results))}
+	for _, result := range results {
+		manifest.Files[result.Path] = result.Hash
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return SaveOutput(string(data)+"\n", path)
+}
+
+// recordManifest saves the manifest of a --delta run that included
+// results. A failure is only a warning: the output was delivered, the
+// next run just repeats more files.
+func recordManifest(results []FileResult, config *Config) {
+	if err := saveManifest(manifestPath(config), results, config); err != nil {
+		config.Warn("delta", "", "recording the manifest: %v", err)
+	}
+}
+
+// deltaResults keeps the results that are new or changed since the run
+// recorded in the manifest and describes the rest in a section. Without a
+// manifest every file is kept.
+func deltaResults(results []FileResult, config *Config) ([]FileResult, []Section, error) {
+	previous, err := loadManifest(manifestPath(config))
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous == nil {
+		config.Debugf("No previous manifest, including every file")
+		return results, nil, nil
+	}
+
+	var changed []FileResult
+	var unchanged []string
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.Path] = true
+		if hash, ok := previous.Files[result.Path]; ok && hash == result.Hash {
+			unchanged = append(unchanged, result.Path)
+		} else {
+			changed = append(changed, result)
+		}
+	}
+	var removed []string
+	for path := range previous.Files {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+
+	var buffer strings.Builder
+	fmt.Fprintf(&buffer, "Only files changed since run %s (%s) follow: %d changed or new, %d unchanged, %d removed.\n",
+		previous.RunID, previous.Time.Local().Format("2006-01-02 15:04"), len(changed), len(unchanged), len(removed))
+	writeDeltaList(&buffer, "Unchanged, as sent before:", unchanged)
+	writeDeltaList(&buffer, "Removed:", removed)
+	return changed, []Section{{Title: "Changes since the previous run", Content: buffer.String()}}, nil
+}
+
+func writeDeltaList(buffer *strings.Builder, title string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintf(buffer, "\n%s\n", title)
+	for i, path := range paths {
+		if i == maxDeltaListed {
+			fmt.Fprintf(buffer, "... and %d more\n", len(paths)-i)
+			break
+		}
+		fmt.Fprintf(buffer, "%s\n", path)
+	}
+}
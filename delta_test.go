@@ -0,0 +1,109 @@
+// delta_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeltaResults(t *testing.T) {
+	config := defaultConfig()
+	config.RunID = "first"
+	config.Manifest = filepath.Join(t.TempDir(), "manifests", "m.json")
+
+	first := []FileResult{
+		{Path: "a.go", Hash: "1"},
+		{Path: "b.go", Hash: "2"},
+		{Path: "c.go", Hash: "3"},
+	}
+	kept, sections, err := deltaResults(first, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 3 || sections != nil {
+		t.Fatalf("without a manifest got %d files and %d sections, want 3 and none", len(kept), len(sections))
+	}
+	if err := saveManifest(manifestPath(config), first, config); err != nil {
+		t.Fatal(err)
+	}
+
+	second := []FileResult{
+		{Path: "a.go", Hash: "1"},
+		{Path: "b.go", Hash: "changed"},
+		{Path: "d.go", Hash: "4"},
+	}
+	kept, sections, err = deltaResults(second, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, result := range kept {
+		paths = append(paths, result.Path)
+	}
+	if got := strings.Join(paths, ","); got != "b.go,d.go" {
+		t.Errorf("kept %s, want b.go,d.go", got)
+	}
+	if len(sections) != 1 {
+		t.Fatalf("got %d sections, want 1", len(sections))
+	}
+	content := sections[0].Content
+	for _, want := range []string{"run first", "2 changed or new, 1 unchanged, 1 removed", "Unchanged, as sent before:\na.go\n", "Removed:\nc.go\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("section lacks %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestSaveManifestReplacesAtomically(t *testing.T) {
+	config := defaultConfig()
+	path := filepath.Join(t.TempDir(), "m.json")
+	for _, hash := range []string{"1", "2"} {
+		config.RunID = "run-" + hash
+		if err := saveManifest(path, []FileResult{{Path: "a.go", Hash: hash}}, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest, err := loadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.RunID != "run-2" || manifest.Files["a.go"] != "2" {
+		t.Errorf("got run %s with %v, want run-2 with a.go=2", manifest.RunID, manifest.Files)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+	if err := saveManifest("", nil, config); err == nil {
+		t.Error("saving without a path succeeded")
+	}
+}
+
+func TestManifestPathFollowsFilters(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_MANIFESTS", t.TempDir())
+	config := defaultConfig()
+	base := manifestPath(config)
+
+	config.IncludeExts = []string{"md", "go"}
+	withExts := manifestPath(config)
+	if withExts == base {
+		t.Error("--include-ext did not change the manifest")
+	}
+	config.IncludeExts = []string{"go", "md"}
+	if manifestPath(config) != withExts {
+		t.Error("the order of --include-ext changed the manifest")
+	}
+	config.IgnoreDirs = []string{"vendor"}
+	if manifestPath(config) == withExts {
+		t.Error("--ignore-dir did not change the manifest")
+	}
+	config.Manifest = "explicit.json"
+	if manifestPath(config) != "explicit.json" {
+		t.Error("--manifest was not used as is")
+	}
+}
@@ -0,0 +1,128 @@
+// deps.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type goModule struct {
+	Path    string
+	Version string
+	Dir     string
+	Error   string
+}
+
+// processDependencies includes the source of the requested dependencies.
+// Each spec is an import path with an optional @version; when the import
+// path points into a module, only that package directory is included.
+func processDependencies(config *Config) ([]FileResult, error) {
+	var results []FileResult
+
+	for _, spec := range config.IncludeDeps {
+		module, pkgDir, err := locateDependency(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		root := filepath.Join(module.Dir, filepath.FromSlash(pkgDir))
+		config.Debugf("Including dependency %s@%s from %s", module.Path, module.Version, root)
+
+		depConfig := *config
+		depConfig.Dirs = []string{root}
+		depConfig.IncludeDeps = nil
+		depConfig.IncludeStd = nil
+		depConfig.OnlyClasses = nil
+		depConfig.tail = nil
+		depResults, err := ProcessFiles(&depConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		// Paths are relative to root as ProcessFiles walked it, which is
+		// relative when root lies below the working directory.
+		walked := canonicalDirs(depConfig.Dirs)[0]
+		for _, result := range depResults {
+			rel, err := filepath.Rel(walked, filepath.FromSlash(result.Path))
+			if err != nil {
+				return nil, err
+			}
+			result.Path = path.Join(module.Path+"@"+module.Version, pkgDir, filepath.ToSlash(rel))
+			result.Source = "dep"
+			result.Class = "vendored"
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// locateDependency finds the module containing the import path in spec,
+// downloading it into the module cache if needed, and returns the package
+// directory relative to the module root.
+func locateDependency(spec string) (*goModule, string, error) {
+	importPath, version, hasVersion := strings.Cut(spec, "@")
+
+	var firstErr error
+	candidate := importPath
+	for {
+		var module *goModule
+		var err error
+		if hasVersion {
+			module, err = goModDownload(candidate + "@" + version)
+		} else {
+			module, err = goListModule(candidate)
+		}
+		if err == nil {
+			return module, strings.TrimPrefix(strings.TrimPrefix(importPath, candidate), "/"), nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		parent := path.Dir(candidate)
+		if parent == "." || parent == candidate {
+			return nil, "", fmt.Errorf("locating dependency %s: %w", spec, firstErr)
+		}
+		candidate = parent
+	}
+}
+
+func goModDownload(query string) (*goModule, error) {
+	out, err := exec.Command("go", "mod", "download", "-json", query).Output()
+	module := &goModule{}
+	if jsonErr := json.Unmarshal(out, module); jsonErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, jsonErr
+	}
+	if module.Error != "" {
+		return nil, fmt.Errorf("%s", module.Error)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// goListModule resolves a module required by the current go.mod, so the
+// version matches what the project builds against.
+func goListModule(modulePath string) (*goModule, error) {
+	out, err := exec.Command("go", "list", "-m", "-json", modulePath).Output()
+	if err != nil {
+		return nil, err
+	}
+	module := &goModule{}
+	if err := json.Unmarshal(out, module); err != nil {
+		return nil, err
+	}
+	if module.Dir == "" {
+		return goModDownload(module.Path + "@" + module.Version)
+	}
+	return module, nil
+}
@@ -0,0 +1,12 @@
+// device_other.go
+//go:build !unix
+
+package main
+
+import "os"
+
+// deviceID is not available on this platform, so -one-file-system never
+// stops the walk.
+func deviceID(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,18 @@
+// device_unix.go
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// deviceID returns the device a file lives on.
+func deviceID(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
@@ -0,0 +1,34 @@
+// diskspace.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// diskReserve is kept free on top of every estimate for filesystem
+// overhead and whatever else is writing to the disk.
+const diskReserve = 16 << 20
+
+// ensureDiskSpace fails early when the filesystem holding dir (or its
+// nearest existing parent) cannot take need more bytes, instead of dying
+// halfway through a write. Free space that cannot be determined passes.
+func ensureDiskSpace(dir string, need int64) error {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+
+	free, ok := freeDiskSpace(dir)
+	if !ok || free >= uint64(need)+diskReserve {
+		return nil
+	}
+	return fmt.Errorf("not enough disk space in %s: need about %s, %s available", dir, formatSize(need), formatSize(int64(free)))
+}
@@ -0,0 +1,316 @@
+// e2e_test.go
+package e2e
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// binary is the codexgigantus executable built once for all tests.
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "codexgigantus-e2e-")
+	if err != nil {
+		panic(err)
+	}
+	binary = filepath.Join(dir, "codexgigantus")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	build := exec.Command("go", "build", "-o", binary, "..")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		os.RemoveAll(dir)
+		panic("building codexgigantus: " + err.Error())
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// sampleRepo copies the bundled sample tree into a temporary directory so
+// commands that write files cannot touch the checkout.
+func sampleRepo(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join("..", "testdata", "corpus")
+	dst := t.TempDir()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+// run executes the binary in dir and returns its stdout and exit code.
+func run(t *testing.T, dir string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CODEXGIGANTUS_DEFAULTS=none")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+func mustRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, code := run(t, dir, args...)
+	if code != 0 {
+		t.Fatalf("%v exited with %d:\n%s", args, code, out)
+	}
+	return out
+}
+
+func TestDefaultTextOutput(t *testing.T) {
+	out := mustRun(t, sampleRepo(t))
+	for _, path := range []string{"README.md", "main.go", "notes.txt", "docs/guide.md", "scripts/build.sh"} {
+		if !strings.Contains(out, "File: "+path+"\n") {
+			t.Errorf("missing header for %s", path)
+		}
+	}
+	if !strings.Contains(out, "\\File: not-a-file.go") {
+		t.Error("delimiter-like content line was not escaped")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	repo := sampleRepo(t)
+
+	out := mustRun(t, repo, "-include-ext", "go")
+	if !strings.Contains(out, "File: main.go") || strings.Contains(out, "File: README.md") {
+		t.Errorf("-include-ext go:\n%s", out)
+	}
+
+	out = mustRun(t, repo, "-ignore-dir", "docs,scripts", "-ignore-ext", "md")
+	if strings.Contains(out, "guide.md") || strings.Contains(out, "build.sh") || strings.Contains(out, "README.md") {
+		t.Errorf("ignored files in output:\n%s", out)
+	}
+
+	out = mustRun(t, repo, "-recursive=false")
+	if strings.Contains(out, "docs/guide.md") {
+		t.Errorf("-recursive=false descended:\n%s", out)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	out := mustRun(t, sampleRepo(t), "-format", "json")
+	var parsed struct {
+		Files []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if len(parsed.Files) != 5 {
+		t.Errorf("got %d files, want 5", len(parsed.Files))
+	}
+}
+
+func TestSaveOutput(t *testing.T) {
+	repo := sampleRepo(t)
+	out := mustRun(t, repo, "-save", "-output-file", "dump.txt", "-show-size")
+	if !strings.Contains(out, "Output saved to dump.txt") || !strings.Contains(out, "Total size:") {
+		t.Errorf("unexpected stdout:\n%s", out)
+	}
+	data, err := os.ReadFile(filepath.Join(repo, "dump.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "File: main.go") {
+		t.Errorf("saved dump is missing main.go:\n%s", data)
+	}
+}
+
+func TestInvalidFlagsFail(t *testing.T) {
+	repo := sampleRepo(t)
+	for _, args := range [][]string{
+		{"-format", "nope"},
+		{"-tokenizer", "nope"},
+		{"-prompt-template", "nope"},
+		{"-dir", "does-not-exist"},
+	} {
+		out, code := run(t, repo, args...)
+		if code == 0 {
+			t.Errorf("%v succeeded:\n%s", args, out)
+		}
+		if !strings.Contains(out, "Error") {
+			t.Errorf("%v printed no error:\n%s", args, out)
+		}
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	repo := sampleRepo(t)
+	mustRun(t, repo, "-include-ext", "md", "-format", "aider", "-save-config", "profile.json")
+	out := mustRun(t, repo, "-config", "profile.json")
+	if !strings.Contains(out, "README.md\n````markdown") || strings.Contains(out, "main.go") {
+		t.Errorf("config file settings were not applied:\n%s", out)
+	}
+}
+
+func TestRestoreRoundTrip(t *testing.T) {
+	repo := sampleRepo(t)
+	for _, format := range []string{"text", "json", "csv", "tsv", "repomix", "aider"} {
+		t.Run(format, func(t *testing.T) {
+			dump := filepath.Join(t.TempDir(), "dump")
+			mustRun(t, repo, "-format", format, "-include-ext", "go,md,sh", "-save", "-output-file", dump)
+
+			into := t.TempDir()
+			mustRun(t, repo, "restore", dump, "-into", into)
+			for _, path := range []string{"README.md", "main.go", "docs/guide.md", "scripts/build.sh"} {
+				want, _ := os.ReadFile(filepath.Join(repo, path))
+				got, err := os.ReadFile(filepath.Join(into, path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != string(want) {
+					t.Errorf("%s differs after restore:\n%s", path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreWrappedDump(t *testing.T) {
+	repo := sampleRepo(t)
+	for _, format := range []string{"text", "json", "aider"} {
+		t.Run(format, func(t *testing.T) {
+			dump := filepath.Join(t.TempDir(), "dump")
+			mustRun(t, repo, "-format", format, "-include-ext", "go,md,sh", "-wrap", "20", "-save", "-output-file", dump)
+
+			if out, code := run(t, repo, "restore", dump, "-into", t.TempDir()); code == 0 {
+				t.Fatalf("restoring a wrapped dump without -wrap succeeded:\n%s", out)
+			}
+			into := t.TempDir()
+			mustRun(t, repo, "restore", dump, "-into", into, "-wrap", "20")
+			for _, path := range []string{"main.go", "scripts/build.sh"} {
+				want, _ := os.ReadFile(filepath.Join(repo, path))
+				if got, _ := os.ReadFile(filepath.Join(into, path)); string(got) != string(want) {
+					t.Errorf("%s differs after restore:\n%s", path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreRefusesTruncatedFiles(t *testing.T) {
+	repo := sampleRepo(t)
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			dump := filepath.Join(t.TempDir(), "dump")
+			mustRun(t, repo, "-format", format, "-include-ext", "go,md", "-max-file-size", "125", "-save", "-output-file", dump)
+
+			into := t.TempDir()
+			out, code := run(t, repo, "restore", dump, "-into", into)
+			if code == 0 || !strings.Contains(out, "SKIPPED: main.go: truncated") {
+				t.Errorf("truncated main.go was not refused (exit %d):\n%s", code, out)
+			}
+			if _, err := os.Stat(filepath.Join(into, "main.go")); err == nil {
+				t.Error("truncated main.go was restored")
+			}
+			if _, err := os.Stat(filepath.Join(into, "docs", "guide.md")); err != nil {
+				t.Errorf("complete file not restored: %v", err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	repo := sampleRepo(t)
+	response := filepath.Join(t.TempDir(), "response.md")
+	answer := "Here is the fix:\n\nnotes.txt\n```\nrewritten\n```\n"
+	if err := os.WriteFile(response, []byte(answer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mustRun(t, repo, "apply", "-dry-run", response)
+	if data, _ := os.ReadFile(filepath.Join(repo, "notes.txt")); string(data) != "no trailing newline" {
+		t.Fatalf("-dry-run changed the file: %q", data)
+	}
+
+	mustRun(t, repo, "apply", "-backup", response)
+	if data, _ := os.ReadFile(filepath.Join(repo, "notes.txt")); string(data) != "rewritten\n" {
+		t.Errorf("apply wrote %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "notes.txt.orig")); err != nil {
+		t.Errorf("no backup: %v", err)
+	}
+}
+
+func TestApplyCreatesFileFromDiff(t *testing.T) {
+	repo := sampleRepo(t)
+	response := filepath.Join(t.TempDir(), "response.md")
+	answer := "```diff\n" +
+		"--- /dev/null\n+++ b/added.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n" +
+		"--- /dev/null\n+++ b/bare.txt\n@@ -0,0 +1 @@\n+bare\n\\ No newline at end of file\n" +
+		"```\n"
+	if err := os.WriteFile(response, []byte(answer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mustRun(t, repo, "apply", response)
+	for name, want := range map[string]string{"added.txt": "one\ntwo\n", "bare.txt": "bare"} {
+		if data, _ := os.ReadFile(filepath.Join(repo, name)); string(data) != want {
+			t.Errorf("%s: got %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	repo := sampleRepo(t)
+	out := mustRun(t, repo, "plan", "-map-only")
+	if !strings.HasPrefix(out, "[1] README.md: ") || !strings.Contains(out, "[3] main.go: ") {
+		t.Errorf("unexpected codemap:\n%s", out)
+	}
+
+	selection := filepath.Join(t.TempDir(), "selection")
+	if err := os.WriteFile(selection, []byte("3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = mustRun(t, repo, "plan", "-select", selection)
+	if !strings.Contains(out, "File: main.go") || strings.Contains(out, "File: README.md") {
+		t.Errorf("selection not applied:\n%s", out)
+	}
+}
+
+func TestTokenBudgetChunks(t *testing.T) {
+	out := mustRun(t, sampleRepo(t), "-max-tokens", "80")
+	if !strings.Contains(out, "=== Chunk 1/") {
+		t.Errorf("no chunks with a small budget:\n%s", out)
+	}
+
+	if out, code := run(t, sampleRepo(t), "-format", "json", "-max-tokens", "80"); code == 0 {
+		t.Errorf("json chunks written to stdout:\n%s", out)
+	}
+}
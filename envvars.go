@@ -0,0 +1,126 @@
+// envvars.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// envUsageRe matches environment variable reads with a literal name in Go,
+// JavaScript/TypeScript, Python and Ruby; the name is the first non-empty
+// group.
+var envUsageRe = regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\("([A-Za-z_][A-Za-z0-9_]*)"\)` +
+	`|(?:process\.env|import\.meta\.env)\.([A-Za-z_][A-Za-z0-9_]*)` +
+	`|process\.env\[['"]([A-Za-z_][A-Za-z0-9_]*)['"]\]` +
+	`|os\.(?:environ\.get|getenv)\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]` +
+	`|os\.environ\[['"]([A-Za-z_][A-Za-z0-9_]*)['"]\]` +
+	`|ENV(?:\.fetch\(|\[)\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`)
+
+var envExampleLineRe = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// envExampleNames are the files that document the expected variables.
+var envExampleNames = []string{".env.example", ".env.sample", ".env.template", ".env.dist", "example.env"}
+
+func isEnvExample(path string) bool {
+	base := filepath.Base(path)
+	for _, name := range envExampleNames {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// envVarsSection lists the environment variables the included code reads,
+// with where they are read and whether an .env.example documents them.
+// Example files are taken from the results or, when filtered out, from the
+// root of each configured directory.
+func envVarsSection(results []FileResult, config *Config) (Section, bool) {
+	used := make(map[string][]string)
+	documented := make(map[string]bool)
+	examples := make(map[string]bool)
+
+	addExample := func(path, content string) {
+		examples[filepath.ToSlash(path)] = true
+		for _, line := range strings.Split(content, "\n") {
+			if m := envExampleLineRe.FindStringSubmatch(line); m != nil {
+				documented[m[1]] = true
+			}
+		}
+	}
+
+	for _, result := range results {
+		if isEnvExample(result.Path) {
+			addExample(result.Path, result.Content)
+			continue
+		}
+		for i, line := range strings.Split(result.Content, "\n") {
+			for _, m := range envUsageRe.FindAllStringSubmatch(line, -1) {
+				for _, name := range m[1:] {
+					if name != "" {
+						used[name] = append(used[name], fmt.Sprintf("%s:%d", filepath.ToSlash(result.Path), i+1))
+						break
+					}
+				}
+			}
+		}
+	}
+	for _, dir := range canonicalDirs(config.Dirs) {
+		for _, name := range envExampleNames {
+			path := filepath.Join(dir, name)
+			if examples[filepath.ToSlash(path)] {
+				continue
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				addExample(path, string(data))
+			}
+		}
+	}
+
+	if len(used) == 0 && len(documented) == 0 {
+		return Section{}, false
+	}
+
+	names := make(map[string]bool)
+	for name := range used {
+		names[name] = true
+	}
+	for name := range documented {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var buffer strings.Builder
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VARIABLE\tDOCUMENTED\tREAD IN")
+	for _, name := range sorted {
+		status := "no"
+		if documented[name] {
+			status = "yes"
+		}
+		locations := used[name]
+		where := strings.Join(locations, ", ")
+		if len(locations) > 5 {
+			where = strings.Join(locations[:5], ", ") + fmt.Sprintf(" and %d more", len(locations)-5)
+		}
+		if len(locations) == 0 {
+			where = "never read"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", name, status, where)
+	}
+	writer.Flush()
+
+	if len(examples) == 0 {
+		buffer.WriteString("No .env.example file found.\n")
+	}
+	return Section{Title: "Environment variables", Content: buffer.String()}, true
+}
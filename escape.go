@@ -0,0 +1,117 @@
+// escape.go
+package main
+
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// escapeDelimiterLines prefixes a backslash to every content line that would
+// be read as a delimiter, including lines that already are escaped
+// delimiters, so unescapeDelimiterLines restores the content exactly.
+func escapeDelimiterLines(content string, isDelimiter func(string) bool) string {
+	if isDelimiter == nil {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	changed := false
+	for i, line := range lines {
+		if isDelimiter(strings.TrimLeft(line, `\`)) {
+			lines[i] = `\` + line
+			changed = true
+		}
+	}
+
+	if !changed {
+		return content
+	}
+	return strings.Join(lines, "\n")
+}
+
+func unescapeDelimiterLines(content string, isDelimiter func(string) bool) string {
+	if isDelimiter == nil {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, `\`) && isDelimiter(strings.TrimLeft(line, `\`)) {
+			lines[i] = line[1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textDelimiter reports lines the text format would treat as a file header
+// or footer.
+func textDelimiter(fileHeader, fileFooter string) func(string) bool {
+	var patterns []*regexp.Regexp
+	for _, template := range []string{fileHeader, fileFooter} {
+		if template == "" {
+			continue
+		}
+		if re, err := delimiterPattern(template); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+
+	return func(line string) bool {
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// escapeXMLAttr escapes s for a double-quoted XML attribute, such as the
+// path of a repomix <file> element.
+func escapeXMLAttr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func unescapeXMLAttr(s string) string {
+	return html.UnescapeString(s)
+}
+
+func repomixDelimiter(line string) bool {
+	return line == "</file>" || strings.HasPrefix(line, "<file path=")
+}
+
+// delimiterPattern turns a header or footer template into an anchored
+// regexp; {path} becomes the first capture group.
+func delimiterPattern(template string) (*regexp.Regexp, error) {
+	pattern := regexp.QuoteMeta(template)
+	pattern = strings.Replace(pattern, `\{path\}`, `(.+?)`, 1)
+	pattern = placeholderRe.ReplaceAllString(pattern, `.*?`)
+	return regexp.Compile("^" + pattern + "$")
+}
+
+// codeFence returns a backtick fence longer than any backtick run at the
+// start of a content line, so the content cannot close the block early.
+func codeFence(content string) string {
+	longest := 0
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimLeft(line, " ")
+		run := len(line) - len(strings.TrimLeft(line, "`"))
+		if run > longest {
+			longest = run
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// isClosingFence reports whether line closes a block opened with fence.
+func isClosingFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, fence) && strings.Trim(line, "`") == ""
+}
@@ -2,68 +2,226 @@
 package main
 
 import (
-	"os"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 func ProcessFiles(config *Config) ([]FileResult, error) {
 	var results []FileResult
 
-	for _, dir := range config.Dirs {
-		if config.Debug {
-			Debug("Processing directory: %s", dir)
+	if config.Tail && config.tail == nil {
+		tail, err := openTail(config)
+		if err != nil {
+			return nil, err
 		}
-		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		config.tail = tail
+	}
 
-			// Handle directories
-			if info.IsDir() {
-				if shouldIgnoreDir(path, config) {
-					if config.Debug {
-						Debug("Ignoring directory: %s", path)
-					}
-					return filepath.SkipDir
-				}
-				if !config.Recursive && path != dir {
-					return filepath.SkipDir
-				}
+	// The canonical form stays local, so config keeps the directories as
+	// given for later runs and --save-config.
+	seen := make(map[string]string)
+	for _, dir := range canonicalDirs(config.Dirs) {
+		config.Debugf("Processing directory: %s", dir)
+		dirResults, err := walkFS(osFS(dir), dir, config)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, uniqueFiles(dirResults, dir, seen, config)...)
+	}
+
+	if len(config.IncludeDeps) > 0 {
+		deps, err := processDependencies(config)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			config.tail.add(dep, config)
+		}
+		results = append(results, deps...)
+	}
+
+	if len(config.IncludeStd) > 0 {
+		std, err := processStdlib(config)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range std {
+			config.tail.add(result, config)
+		}
+		results = append(results, std...)
+	}
+
+	return finishResults(results, config), nil
+}
+
+// ProcessFS runs the file filters over any fs.FS, such as an fstest.MapFS,
+// a zip archive or an embedded tree. Paths are reported as they appear in
+// fsys. Symlinks cannot be resolved through fs.FS, so files are not checked
+// for duplicates the way ProcessFiles does.
+func ProcessFS(fsys fs.FS, config *Config) ([]FileResult, error) {
+	results, err := walkFS(fsys, ".", config)
+	if err != nil {
+		return nil, err
+	}
+	return finishResults(results, config), nil
+}
+
+// finishResults runs the steps that follow the walk, the same for
+// ProcessFiles and ProcessFS.
+func finishResults(results []FileResult, config *Config) []FileResult {
+	results = filterClasses(results, config)
+	if config.Migrations {
+		results = consolidateMigrations(results, config)
+	}
+	if len(config.Priorities) > 0 {
+		results = prioritizeResults(results, config)
+	}
+	if config.BuildSection {
+		results = prioritizeBuildFiles(results)
+	}
+
+	if config.DedupeLicenses {
+		results = dedupeLicenseHeaders(results, config)
+	}
+
+	reportCaseCollisions(results, config)
+	return results
+}
+
+// walkFS collects the files of fsys that pass the filters. dir is the name
+// fsys was opened from; unlabelled results report their paths below it.
+func walkFS(fsys fs.FS, dir string, config *Config) ([]FileResult, error) {
+	pipeline := newFilePipeline(fsys, config)
+
+	rootDevice, checkDevice := uint64(0), false
+	if config.OneFileSystem {
+		if info, err := fs.Stat(fsys, "."); err == nil {
+			rootDevice, checkDevice = deviceID(info)
+		}
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && name != "." {
+				config.Warn("vanished", path, "disappeared during processing, skipped")
 				return nil
 			}
+			return err
+		}
 
-			// Handle files
-			if shouldIgnoreFile(path, config) {
-				if config.Debug {
-					Debug("Ignoring file: %s", path)
-				}
+		// Handle directories
+		if entry.IsDir() {
+			if shouldIgnoreDir(path, config) || anchoredMatch(name, config.IgnoreDirs) {
+				config.Debugf("Ignoring directory: %s", path)
+				return fs.SkipDir
+			}
+			if name == "." {
 				return nil
 			}
+			if !config.Recursive {
+				return fs.SkipDir
+			}
+			if checkDevice {
+				if info, err := entry.Info(); err == nil {
+					if device, ok := deviceID(info); ok && device != rootDevice {
+						config.Debugf("Not crossing into another filesystem: %s", path)
+						return fs.SkipDir
+					}
+				}
+			}
+			return nil
+		}
 
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return err
+		// WalkDir does not descend through symlinked directories, and
+		// reading one as a file would fail the run.
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, err := fs.Stat(fsys, name); err == nil && info.IsDir() {
+				config.Debugf("Not following directory symlink: %s", path)
+				return nil
 			}
+		}
 
-			results = append(results, FileResult{
-				Path:    path,
-				Content: string(content),
-			})
+		// Handle files
+		if shouldIgnoreFile(path, config) || anchoredMatch(name, config.IgnoreFiles) {
+			config.Debugf("Ignoring file: %s", path)
+			return nil
+		}
+		// With --network-fs the check runs on the content once it is read.
+		if !config.NetworkFS && !matchesPlatform(fsys, name, config) {
+			config.Debugf("Ignoring file built for another platform: %s", path)
+			return nil
+		}
 
+		// Labelled sources such as clones report paths relative to
+		// their root instead of the temporary checkout location.
+		if config.Source != "" {
+			pipeline.submit(name, name, config.Source)
 			return nil
-		})
-		if err != nil {
-			return nil, err
 		}
+		// Warnings report the path as the result will (see
+		// NewFileResult).
+		pipeline.submit(name, filepath.ToSlash(path), "fs")
+		return nil
+	})
+	// The workers have to finish even when the walk failed.
+	results, readErr := pipeline.wait()
+	if err != nil {
+		return nil, err
 	}
+	return results, readErr
+}
+
+var errFileChanged = errors.New("file changed while being read")
 
-	return results, nil
+// readStable reads name and compares its size and modification time before
+// and after, retrying once when a writer got in between so torn content is
+// never emitted.
+func readStable(fsys fs.FS, name string) ([]byte, fs.FileInfo, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		before, err := fs.Stat(fsys, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		after, err := fs.Stat(fsys, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if before.Size() == after.Size() && before.ModTime().Equal(after.ModTime()) && int64(len(content)) == after.Size() {
+			return content, after, nil
+		}
+	}
+	return nil, nil, errFileChanged
 }
 
 func shouldIgnoreDir(path string, config *Config) bool {
 	for _, ignoreDir := range config.IgnoreDirs {
-		if strings.Contains(path, ignoreDir) {
+		if !strings.HasPrefix(ignoreDir, "/") && strings.Contains(path, ignoreDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// anchoredMatch applies the rules starting with a slash, which name a path
+// below the walked directory, to rel, a slash-separated path relative to
+// it: /docs matches docs and everything in it, but not src/docs or mydocs.
+func anchoredMatch(rel string, rules []string) bool {
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule, "/") {
+			continue
+		}
+		if anchored := strings.Trim(rule, "/"); anchored != "" && (rel == anchored || strings.HasPrefix(rel, anchored+"/")) {
 			return true
 		}
 	}
@@ -103,6 +261,55 @@ func shouldIgnoreFile(path string, config *Config) bool {
 }
 
 type FileResult struct {
-	Path    string
-	Content string
+	Path       string    `json:"path"`
+	Content    string    `json:"content"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time,omitempty"`
+	Language   string    `json:"language,omitempty"`
+	Class      string    `json:"class,omitempty"`
+	Hash       string    `json:"hash"`
+	Source     string    `json:"source"`
+	TokenCount int       `json:"token_count"`
+	Truncated  bool      `json:"truncated,omitempty"`
+	Coverage   *float64  `json:"coverage,omitempty"`
+}
+
+// NewFileResult fills in the metadata for a file read from source ("fs",
+// "dep", "std" or a label such as "git:org/repo@ref"). Size and Hash describe the file on disk, even when the
+// content is truncated to config.MaxFileSize. The path uses forward
+// slashes whatever the source.
+func NewFileResult(path, source string, content []byte, modTime time.Time, config *Config) FileResult {
+	return newFileResult(path, path, source, content, modTime, config)
+}
+
+// newFileResult is NewFileResult for a file found by a walk, where name is
+// its path below the walked directory. The class is taken from name, so
+// the directories leading to the walked one (testdata/, docs/) do not
+// count.
+func newFileResult(path, name, source string, content []byte, modTime time.Time, config *Config) FileResult {
+	path = filepath.ToSlash(path)
+	sum := sha256.Sum256(content)
+	result := FileResult{
+		Path:     path,
+		Size:     int64(len(content)),
+		ModTime:  modTime,
+		Language: detectLanguage(path),
+		Class:    classifyFile(name, source, content),
+		Hash:     hex.EncodeToString(sum[:]),
+		Source:   source,
+	}
+
+	if config.MaxFileSize > 0 && int64(len(content)) > config.MaxFileSize {
+		cut := int(config.MaxFileSize)
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut]
+		result.Truncated = true
+		config.Warn("truncated", path, "truncated to %d of %d bytes", cut, result.Size)
+	}
+	result.Content = applyTransforms(string(content), path, config)
+	result.TokenCount = estimateTokens(result.Content, config.Tokenizer)
+
+	return result
 }
@@ -0,0 +1,72 @@
+// file_processor_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessFSMatchesProcessFiles(t *testing.T) {
+	// A tree where every post-walk step changes something.
+	tree := t.TempDir()
+	license := "// Copyright 2024 Acme Inc. Licensed under the MIT License.\n\npackage x\n"
+	for name, content := range map[string]string{
+		"a.go": license, "b.go": license, "tools/Makefile": "build:\n\tgo build\n",
+		"README.md": "# x\n", "docs/readme.md": "# y\n", "docs/README.MD": "# z\n",
+	} {
+		path := filepath.Join(tree, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := map[string]string{"tree": tree}
+	for _, corpus := range []string{"buildfiles", "corpus", "migrations"} {
+		dirs[corpus] = filepath.Join("testdata", corpus)
+	}
+	for name, dir := range dirs {
+		t.Run(name, func(t *testing.T) {
+			newConfig := func() *Config {
+				config := defaultConfig()
+				config.BuildSection = true
+				config.DedupeLicenses = true
+				config.Migrations = true
+				config.Priorities = map[string]int{"*.md": 5}
+				config.Warnings = &Warnings{}
+				return config
+			}
+			fsConfig := newConfig()
+			fromFS, err := ProcessFS(os.DirFS(dir), fsConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := newConfig()
+			config.Dirs = []string{dir}
+			fromFiles, err := ProcessFiles(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fromFS) != len(fromFiles) {
+				t.Fatalf("ProcessFS found %d files, ProcessFiles %d", len(fromFS), len(fromFiles))
+			}
+			for i := range fromFS {
+				// Paths, also those consolidated migrations mention, differ
+				// by the walked directory.
+				path := strings.TrimPrefix(fromFiles[i].Path, filepath.ToSlash(dir)+"/")
+				content := strings.ReplaceAll(fromFiles[i].Content, filepath.ToSlash(dir)+"/", "")
+				if fromFS[i].Path != path || fromFS[i].Content != content || fromFS[i].Class != fromFiles[i].Class {
+					t.Errorf("result %d: ProcessFS gave %s (%s), ProcessFiles %s (%s)", i, fromFS[i].Path, fromFS[i].Class, path, fromFiles[i].Class)
+				}
+			}
+			if a, b := len(fsConfig.Warnings.List()), len(config.Warnings.List()); a != b {
+				t.Errorf("ProcessFS raised %d warnings, ProcessFiles %d", a, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// formats.go
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Section is an extra block of context, such as the recent commit log,
+// written ahead of the file contents.
+type Section struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+var outputFormats = []string{"text", "json", "csv", "tsv", "repomix", "aider", "codemap", "report", "json-result"}
+
+func isValidFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func renderSections(sections []Section, format string) string {
+	var buffer bytes.Buffer
+
+	for _, section := range sections {
+		content := strings.TrimRight(section.Content, "\n")
+		switch format {
+		case "repomix":
+			tag := strings.ReplaceAll(strings.ToLower(section.Title), " ", "_")
+			buffer.WriteString(fmt.Sprintf("<%s>\n%s\n</%s>\n\n", tag, content, tag))
+		case "aider":
+			buffer.WriteString(fmt.Sprintf("## %s\n\n%s\n\n", section.Title, content))
+		default:
+			buffer.WriteString(fmt.Sprintf("=== %s ===\n%s\n\n", section.Title, content))
+		}
+	}
+
+	return buffer.String()
+}
+
+// formatRepomix mirrors the XML style written by repomix so prompts and
+// scripts built around its output keep working unchanged.
+func formatRepomix(results []FileResult) string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("This file is a merged representation of the codebase, combined into a single document by codexgigantus.\n\n")
+	buffer.WriteString("<directory_structure>\n")
+	for _, result := range results {
+		buffer.WriteString(filepath.ToSlash(result.Path) + "\n")
+	}
+	buffer.WriteString("</directory_structure>\n\n")
+
+	buffer.WriteString("<files>\n")
+	buffer.WriteString("This section contains the contents of the repository's files.\n\n")
+	for _, result := range results {
+		content := escapeDelimiterLines(result.Content, repomixDelimiter)
+		buffer.WriteString(fmt.Sprintf("<file path=\"%s\">\n", escapeXMLAttr(filepath.ToSlash(result.Path))))
+		buffer.WriteString(content)
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString("</file>\n\n")
+	}
+	buffer.WriteString("</files>\n")
+
+	return buffer.String()
+}
+
+// formatAider uses the "whole file" layout aider expects: the file name on
+// its own line followed by the content in a fenced block.
+func formatAider(results []FileResult) string {
+	var buffer bytes.Buffer
+
+	for _, result := range results {
+		fence := codeFence(result.Content)
+		buffer.WriteString(filepath.ToSlash(result.Path) + "\n")
+		buffer.WriteString(fence + fenceLanguage(result.Path) + "\n")
+		buffer.WriteString(result.Content)
+		if result.Content != "" && !strings.HasSuffix(result.Content, "\n") {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(fence + "\n\n")
+	}
+
+	return buffer.String()
+}
+
+func fenceLanguage(path string) string {
+	if language := detectLanguage(path); language != "" {
+		return language
+	}
+	return strings.TrimPrefix(filepath.Ext(path), ".")
+}
+
+type jsonOutput struct {
+	RunID    string       `json:"run_id,omitempty"`
+	Sections []Section    `json:"sections,omitempty"`
+	Files    []FileResult `json:"files"`
+	Warnings []Warning    `json:"warnings,omitempty"`
+}
+
+func formatJSON(results []FileResult, sections []Section, config *Config) string {
+	if results == nil {
+		results = []FileResult{}
+	}
+	data, err := json.MarshalIndent(jsonOutput{RunID: config.RunID, Sections: sections, Files: results, Warnings: config.Warnings.List()}, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("{\"error\": %q}\n", err.Error())
+	}
+	return string(data) + "\n"
+}
+
+// formatDelimited writes one path,content record per file with a header row,
+// quoting fields as RFC 4180 requires.
+func formatDelimited(results []FileResult, comma rune) string {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+	writer.Comma = comma
+	writer.Write([]string{"path", "content"})
+	for _, result := range results {
+		writer.Write([]string{filepath.ToSlash(result.Path), result.Content})
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
@@ -0,0 +1,31 @@
+// formats_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatRepomixEscapesPaths(t *testing.T) {
+	results := []FileResult{
+		{Path: `say "hi".txt`, Content: "hi\n"},
+		{Path: "a<b>&c.txt", Content: "abc\n"},
+		{Path: "a&amp;b.txt", Content: "amp\n"},
+	}
+	output := formatRepomix(results)
+	for _, want := range []string{`<file path="say &#34;hi&#34;.txt">`, `<file path="a&lt;b&gt;&amp;c.txt">`, `<file path="a&amp;amp;b.txt">`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %s:\n%s", want, output)
+		}
+	}
+
+	got := parseRepomix(output)
+	if len(got) != len(results) {
+		t.Fatalf("restored %d files, want %d", len(got), len(results))
+	}
+	for i, result := range results {
+		if got[i].Path != result.Path || got[i].Content != result.Content {
+			t.Errorf("file %d = %q %q, want %q %q", i, got[i].Path, got[i].Content, result.Path, result.Content)
+		}
+	}
+}
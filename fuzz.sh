@@ -0,0 +1,11 @@
+#!/bin/bash
+
+# Run every fuzz target for a while (default 30s each, e.g. ./fuzz.sh 5m).
+# Failing inputs are saved under testdata/fuzz and replayed by go test.
+
+FUZZTIME=${1:-30s}
+
+for target in $(go test -list '^Fuzz' . | grep '^Fuzz'); do
+    echo "== $target"
+    go test -run '^$' -fuzz "^${target}\$" -fuzztime "$FUZZTIME" . || exit 1
+done
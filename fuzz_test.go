@@ -0,0 +1,160 @@
+// fuzz_test.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// restorable reports whether path can survive a dump: the text-based
+// formats keep the path on a single line and trim surrounding spaces.
+func restorable(path string) bool {
+	return path != "" && utf8.ValidString(path) && !strings.ContainsAny(path, "\r\n\"<>`") && strings.TrimSpace(path) == path
+}
+
+func FuzzShouldIgnoreFile(f *testing.F) {
+	f.Add("src/main.go", "go", "md")
+	f.Add("README", "", "")
+	f.Add("a/b.tar.gz", "gz", "tar.gz")
+	f.Fuzz(func(t *testing.T, path, include, ignore string) {
+		config := defaultConfig()
+		config.IncludeExts = parseCommaSeparated(include)
+		config.IgnoreExts = parseCommaSeparated(ignore)
+		ignored := shouldIgnoreFile(path, config)
+
+		ext := strings.TrimPrefix(filepath.Ext(path), ".")
+		for _, ignoreExt := range config.IgnoreExts {
+			if ext == ignoreExt && !ignored {
+				t.Fatalf("%q has ignored extension %q but was kept", path, ext)
+			}
+		}
+		if len(config.IncludeExts) == 0 && len(config.IgnoreExts) == 0 && ignored {
+			t.Fatalf("%q was ignored without any filter", path)
+		}
+	})
+}
+
+func FuzzValidateConfig(f *testing.F) {
+	f.Add("text", "", "", "")
+	f.Add("json", "bug-report", "cl100k", "slack:C123")
+	f.Add("nope", "nope", "nope", "nope:x")
+	f.Fuzz(func(t *testing.T, format, template, tokenizer, sink string) {
+		config := defaultConfig()
+		config.Format = format
+		config.PromptTemplate = template
+		config.Tokenizer = tokenizer
+		config.Sinks = parseCommaSeparated(sink)
+		err := ValidateConfig(config)
+		if !isValidFormat(format) && err == nil {
+			t.Fatalf("format %q accepted", format)
+		}
+	})
+}
+
+func FuzzParseDelimited(f *testing.F) {
+	f.Add("path,content\na.go,\"x\"\n")
+	f.Add("path\tcontent\n\"\n")
+	f.Fuzz(func(t *testing.T, data string) {
+		parseDelimited(data, ',')
+		parseDelimited(data, '\t')
+	})
+}
+
+func FuzzParseOutput(f *testing.F) {
+	f.Add("File: a.go\npackage a\n\n")
+	f.Add("<files>\n<file path=\"a\">\n</file>\n</files>\n")
+	f.Add("a.go\n```go\nx\n```\n")
+	f.Fuzz(func(t *testing.T, data string) {
+		for _, format := range []string{"auto", "text", "json", "csv", "tsv", "repomix", "aider"} {
+			ParseOutput(data, format, "File: {path}", "")
+		}
+	})
+}
+
+func FuzzParseResponse(f *testing.F) {
+	f.Add("main.go\n```go\npackage main\n```\n")
+	f.Add("--- a/x.go\n+++ b/x.go\n@@ -1,2 +1,2 @@\n-a\n+b\n c\n")
+	f.Fuzz(func(t *testing.T, response string) {
+		for _, change := range ParseResponse(response) {
+			if change.IsDiff {
+				applyHunks("a\nb\nc\n", change.Hunks)
+			}
+		}
+	})
+}
+
+// FuzzRoundTrip checks that every restorable format gives back the files
+// it was generated from, whatever the content looks like.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("a.go", "package a\n", "b/c.md", "File: x\n")
+	f.Add("x.txt", "</file>\n\\</file>\n", "y.txt", "```\n````\n")
+	f.Add("e", "", "f", "\n\n")
+	f.Add("R&D's.txt", "x\n", "a&amp;b", "y\n")
+	f.Fuzz(func(t *testing.T, path1, content1, path2, content2 string) {
+		if !restorable(path1) || !restorable(path2) || path1 == path2 {
+			t.Skip()
+		}
+		// JSON cannot carry invalid UTF-8, and dumps are meant for text.
+		if !utf8.ValidString(content1) || !utf8.ValidString(content2) {
+			t.Skip()
+		}
+		results := []FileResult{{Path: path1, Content: content1}, {Path: path2, Content: content2}}
+
+		for _, format := range []string{"text", "json", "csv", "tsv", "repomix", "aider"} {
+			config := defaultConfig()
+			config.Format = format
+			want := results
+			if format != "json" && format != "csv" && format != "tsv" {
+				// Text-based dumps cannot tell whether a file ended
+				// with a newline, so compare against terminated content.
+				want = []FileResult{{Path: path1, Content: terminated(content1)}, {Path: path2, Content: terminated(content2)}}
+				if format == "text" {
+					// Printing adds newlines after the dump, so trailing
+					// blank lines of the last file are not recoverable.
+					want[1].Content = terminated(strings.TrimRight(want[1].Content, "\n"))
+				}
+			}
+
+			output := GenerateOutput(want, nil, config)
+			got, err := ParseOutput(output, "auto", config.FileHeader, config.FileFooter)
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%s: got %d files, want %d\n%s", format, len(got), len(want), output)
+			}
+			for i := range want {
+				if got[i].Path != want[i].Path || got[i].Content != want[i].Content {
+					t.Fatalf("%s: file %d = %q %q, want %q %q", format, i, got[i].Path, got[i].Content, want[i].Path, want[i].Content)
+				}
+			}
+		}
+	})
+}
+
+func terminated(content string) string {
+	if content == "" || strings.HasSuffix(content, "\n") {
+		return content
+	}
+	return content + "\n"
+}
+
+func FuzzEllipsize(f *testing.F) {
+	f.Add("a short one", 5)
+	f.Add("Größenänderung für alle Einträge", 12)
+	f.Add("日本語のコメント", 8)
+	f.Fuzz(func(t *testing.T, s string, max int) {
+		if !utf8.ValidString(s) || max < 3 || max > 200 {
+			return
+		}
+		got := ellipsize(s, max)
+		if !utf8.ValidString(got) {
+			t.Fatalf("ellipsize(%q, %d) = %q, not valid UTF-8", s, max, got)
+		}
+		if len(got) > max {
+			t.Fatalf("ellipsize(%q, %d) = %q, longer than %d bytes", s, max, got, max)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// git.go
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func isGitRepo(dir string) bool {
+	return exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run() == nil
+}
+
+// gitBranch names the checked-out branch of the first directory, or "" when
+// it is not in a git repository.
+func gitBranch(dirs []string) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+	out, err := exec.Command("git", "-C", dirs[0], "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// recentCommits returns the last n commit messages of the repository that
+// contains dir, limited to commits touching paths when paths is non-empty.
+// Directories outside a git repository yield an empty log.
+func recentCommits(dir string, n int, paths []string) (string, error) {
+	if !isGitRepo(dir) {
+		return "", nil
+	}
+
+	args := []string{"-C", dir, "log", "-n", strconv.Itoa(n), "--date=short", "--format=%h %ad %an%n    %s%n%w(0,4,4)%b"}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("reading git log in %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// includedPaths lists the results below dir relative to it, or nil when
+// filtering by file is disabled.
+func includedPaths(results []FileResult, dir string, enabled bool) []string {
+	if !enabled {
+		return nil
+	}
+
+	var paths []string
+	for _, result := range results {
+		rel, err := filepath.Rel(dir, result.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		paths = append(paths, rel)
+	}
+	return paths
+}
@@ -0,0 +1,127 @@
+// golden_test.go
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Run `go test -run TestGolden -update` after a deliberate format change and
+// review the diff under testdata/golden.
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+var goldenSections = []Section{{Title: "Recent commits", Content: "abc1234 Add greeting\n"}}
+
+var goldenCases = []struct {
+	name string
+	// corpus is the directory under testdata the case runs on (default
+	// corpus).
+	corpus   string
+	sections bool
+	setup    func(config *Config)
+}{
+	{name: "text"},
+	{name: "json", sections: true, setup: func(c *Config) { c.Format = "json" }},
+	{name: "json-result", sections: true, setup: func(c *Config) { c.Format = "json-result" }},
+	{name: "csv", setup: func(c *Config) { c.Format = "csv" }},
+	{name: "tsv", setup: func(c *Config) { c.Format = "tsv" }},
+	{name: "repomix", sections: true, setup: func(c *Config) { c.Format = "repomix" }},
+	{name: "aider", sections: true, setup: func(c *Config) { c.Format = "aider" }},
+	{name: "codemap", setup: func(c *Config) { c.Format = "codemap" }},
+	{name: "report", sections: true, setup: func(c *Config) { c.Format = "report" }},
+	{name: "text-sections", sections: true},
+	{name: "text-header-footer", setup: func(c *Config) {
+		c.FileHeader = "----- {path} ({language}, {size} bytes, {tokens} tokens)"
+		c.FileFooter = "----- end {path}"
+	}},
+	{name: "text-only-class", setup: func(c *Config) {
+		c.OnlyClasses = []string{"docs", "config"}
+		c.FileHeader = "File: {path} ({class})"
+	}},
+	{name: "text-priority", setup: func(c *Config) {
+		c.Priorities = map[string]int{"docs": 10, "*.txt": -5, "scripts/*.sh": 5}
+	}},
+	{name: "text-normalize-data", corpus: "datafiles", setup: func(c *Config) {
+		c.NormalizeData = true
+		c.MaxArrayItems = 3
+	}},
+	{name: "text-build-section", corpus: "buildfiles", setup: func(c *Config) { c.BuildSection = true }},
+	{name: "text-consolidate-migrations", corpus: "migrations", setup: func(c *Config) { c.Migrations = true }},
+	{name: "text-wrap", setup: func(c *Config) { c.WrapColumn = 40 }},
+	{name: "text-max-file-size", setup: func(c *Config) { c.MaxFileSize = 64 }},
+	{name: "text-show-funcs", setup: func(c *Config) { c.ShowFuncs = true }},
+	{name: "text-prompt", setup: func(c *Config) {
+		c.PromptTemplate = "code-review"
+		c.PromptDetails = "Focus on error handling."
+	}},
+	{name: "text-banner", setup: func(c *Config) {
+		c.Banner = true
+		c.BannerText = "Sample dump\n{files} files, about {tokens} tokens"
+	}},
+	{name: "repomix-wrap", setup: func(c *Config) {
+		c.Format = "repomix"
+		c.WrapColumn = 40
+	}},
+	{name: "aider-max-file-size", setup: func(c *Config) {
+		c.Format = "aider"
+		c.MaxFileSize = 64
+	}},
+	{name: "json-include-ext", setup: func(c *Config) {
+		c.Format = "json"
+		c.IncludeExts = []string{"go", "md"}
+		c.Tokenizer = "claude"
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := defaultConfig()
+			if tc.setup != nil {
+				tc.setup(config)
+			}
+			if err := ValidateConfig(config); err != nil {
+				t.Fatal(err)
+			}
+
+			corpus := tc.corpus
+			if corpus == "" {
+				corpus = "corpus"
+			}
+			results, err := ProcessFS(os.DirFS(filepath.Join("testdata", corpus)), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Checkout times differ between machines.
+			for i := range results {
+				results[i].ModTime = time.Time{}
+			}
+			sections, err := BuildSections(results, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.sections {
+				sections = goldenSections
+			}
+			got := GenerateOutput(results, sections, config)
+
+			golden := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestGolden -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s; rerun with -update if the change is intended\n--- got\n%s\n--- want\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// history.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// HistoryEntry is one line of the local history file. Nothing is ever sent
+// anywhere; the file only exists when --history is enabled.
+type HistoryEntry struct {
+	Time     time.Time     `json:"time"`
+	RunID    string        `json:"run_id"`
+	Profile  string        `json:"profile,omitempty"`
+	Dirs     []string      `json:"dirs,omitempty"`
+	Format   string        `json:"format"`
+	Files    int           `json:"files"`
+	Bytes    int64         `json:"bytes"`
+	Tokens   int           `json:"tokens"`
+	Duration time.Duration `json:"duration"`
+	Warnings int           `json:"warnings,omitempty"`
+}
+
+// historyPath returns $CODEXGIGANTUS_HISTORY or history.jsonl next to the
+// per-user defaults.
+func historyPath() string {
+	if path := os.Getenv("CODEXGIGANTUS_HISTORY"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "codexgigantus", "history.jsonl")
+}
+
+// recordHistory appends the stats of a finished run when config.History is
+// set. Failures only produce a warning; the run itself succeeded.
+func recordHistory(results []FileResult, start time.Time, config *Config) {
+	if !config.History {
+		return
+	}
+	entry := HistoryEntry{
+		Time:     start,
+		RunID:    config.RunID,
+		Profile:  config.Profile,
+		Dirs:     config.Dirs,
+		Format:   config.Format,
+		Files:    len(results),
+		Tokens:   totalTokens(results),
+		Duration: time.Since(start).Round(time.Millisecond),
+		Warnings: len(config.Warnings.List()),
+	}
+	for _, result := range results {
+		entry.Bytes += result.Size
+	}
+
+	if err := appendHistory(historyPath(), entry); err != nil {
+		config.Warn("history", "", "recording history: %v", err)
+	}
+}
+
+func appendHistory(path string, entry HistoryEntry) error {
+	if path == "" {
+		return errors.New("no user config directory")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func loadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		// A line cut short by a crash is skipped rather than failing the
+		// whole history.
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	limitFlag := fs.Int("n", 20, "Number of most recent runs to show (0 shows all)")
+	profileFlag := fs.String("profile", "", "Only show runs of this profile")
+	clearFlag := fs.Bool("clear", false, "Delete the history file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := historyPath()
+	if *clearFlag {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		fmt.Println("History cleared")
+		return nil
+	}
+
+	entries, err := loadHistory(path)
+	if err != nil {
+		return err
+	}
+	if *profileFlag != "" {
+		var filtered []HistoryEntry
+		for _, entry := range entries {
+			if entry.Profile == *profileFlag {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = filtered
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No runs recorded in %s; enable recording with --history\n", path)
+		return nil
+	}
+	if *limitFlag > 0 && len(entries) > *limitFlag {
+		entries = entries[len(entries)-*limitFlag:]
+	}
+
+	fmt.Print(formatHistory(entries))
+	return nil
+}
+
+// formatHistory renders entries as a table. The CHANGE column compares the
+// tokens with the previous listed run of the same profile.
+func formatHistory(entries []HistoryEntry) string {
+	var buffer strings.Builder
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "TIME\tPROFILE\tFORMAT\tFILES\tSIZE\tTOKENS\tCHANGE\tDURATION\t")
+
+	previous := map[string]int{}
+	for _, entry := range entries {
+		profile := entry.Profile
+		if profile == "" {
+			profile = "-"
+		}
+		change := ""
+		if tokens, ok := previous[profile]; ok && tokens > 0 {
+			change = fmt.Sprintf("%+.1f%%", float64(entry.Tokens-tokens)*100/float64(tokens))
+		}
+		previous[profile] = entry.Tokens
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%d\t%s\t%s\t\n",
+			entry.Time.Local().Format("2006-01-02 15:04"), profile, entry.Format, entry.Files,
+			formatSize(entry.Bytes), entry.Tokens, change, formatDuration(entry.Duration))
+	}
+	writer.Flush()
+	return buffer.String()
+}
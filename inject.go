@@ -0,0 +1,427 @@
+// inject.go
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileChange is a single edit extracted from an LLM response: either a
+// complete replacement of a file or a list of unified-diff hunks.
+type FileChange struct {
+	Path    string
+	Content string
+	Hunks   []Hunk
+	IsDiff  bool
+	Delete  bool
+}
+
+type Hunk struct {
+	OldStart int
+	Lines    []string
+	// NoNewline is set by a "\ No newline at end of file" marker after a
+	// line of the new version.
+	NoNewline bool
+	// Incomplete is set when the diff ends before the line counts of the
+	// @@ header are used up.
+	Incomplete bool
+}
+
+type applyReport struct {
+	Applied   []string
+	Conflicts []string
+}
+
+var pastTense = map[string]string{
+	"create":  "created",
+	"replace": "replaced",
+	"update":  "updated",
+	"delete":  "deleted",
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+func runApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	dirFlag := fs.String("dir", ".", "Root directory the patches are applied to")
+	dryRunFlag := fs.Bool("dry-run", false, "Report what would change without writing files")
+	backupFlag := fs.Bool("backup", false, "Keep a copy of every modified file with a .orig suffix")
+	sandboxFlag := fs.Bool("sandbox", false, "Apply to a temporary copy first and only promote the changes if the check command succeeds")
+	checkCmdFlag := fs.String("check-cmd", "go build ./...", "Command run inside the sandbox to validate the changes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	response, err := readResponse(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	changes := ParseResponse(response)
+	if len(changes) == 0 {
+		return errors.New("no unified diffs or fenced file blocks found in the response")
+	}
+
+	var report applyReport
+	if *sandboxFlag && !*dryRunFlag {
+		report, err = applyInSandbox(*dirFlag, changes, *checkCmdFlag, *backupFlag)
+	} else {
+		report = ApplyChanges(*dirFlag, changes, *dryRunFlag, *backupFlag)
+	}
+	for _, line := range report.Applied {
+		fmt.Println(line)
+	}
+	for _, line := range report.Conflicts {
+		fmt.Println("CONFLICT:", line)
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Conflicts) > 0 {
+		return fmt.Errorf("%d of %d changes could not be applied", len(report.Conflicts), len(changes))
+	}
+	return nil
+}
+
+func readResponse(path string) (string, error) {
+	if path == "" || path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// ParseResponse extracts unified diffs and fenced file blocks. A fenced
+// block counts as a file when the line before it names the file, either
+// bare, as "File: path", or wrapped in backticks or bold markers.
+func ParseResponse(response string) []FileChange {
+	var changes []FileChange
+	lines := strings.Split(strings.ReplaceAll(response, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			change, next := parseUnifiedDiff(lines, i)
+			changes = append(changes, change)
+			i = next - 1
+			continue
+		}
+
+		if strings.HasPrefix(line, "```") && i > 0 && !strings.HasPrefix(line, "```diff") {
+			path := fileNameFromLine(lines[i-1])
+			if path == "" {
+				continue
+			}
+			fence := line[:len(line)-len(strings.TrimLeft(line, "`"))]
+			var content []string
+			j := i + 1
+			for ; j < len(lines) && !isClosingFence(lines[j], fence); j++ {
+				content = append(content, lines[j])
+			}
+			change := FileChange{Path: path}
+			if len(content) > 0 {
+				change.Content = strings.Join(content, "\n") + "\n"
+			}
+			changes = append(changes, change)
+			i = j
+		}
+	}
+
+	return changes
+}
+
+func parseUnifiedDiff(lines []string, start int) (FileChange, int) {
+	oldPath := diffPath(lines[start][4:])
+	newPath := diffPath(lines[start+1][4:])
+
+	change := FileChange{Path: newPath, IsDiff: true}
+	if newPath == "/dev/null" {
+		change.Path = oldPath
+		change.Delete = true
+	}
+
+	i := start + 2
+	for i < len(lines) {
+		match := hunkHeaderRe.FindStringSubmatch(lines[i])
+		if match == nil {
+			break
+		}
+		oldStart, _ := strconv.Atoi(match[1])
+		oldCount, newCount := hunkCount(match[2]), hunkCount(match[3])
+		hunk := Hunk{OldStart: oldStart}
+		i++
+		// The counts decide where the hunk ends, so removed lines that
+		// look like a file header ("--- old" for "-- old") stay in it.
+	lines:
+		for ; i < len(lines); i++ {
+			line := lines[i]
+			if strings.HasPrefix(line, `\`) {
+				if n := len(hunk.Lines); n > 0 && hunk.Lines[n-1][0] != '-' {
+					hunk.NoNewline = true
+				}
+				continue
+			}
+			if oldCount <= 0 && newCount <= 0 {
+				break
+			}
+			if line == "" {
+				// Some models strip the leading space from empty context lines.
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				oldCount--
+				newCount--
+			case '-':
+				oldCount--
+			case '+':
+				newCount--
+			default:
+				break lines
+			}
+			hunk.Lines = append(hunk.Lines, line)
+		}
+		hunk.Incomplete = oldCount != 0 || newCount != 0
+		change.Hunks = append(change.Hunks, hunk)
+	}
+
+	return change, i
+}
+
+// hunkCount reads a line count of an @@ header, which is 1 when omitted.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func diffPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+func fileNameFromLine(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "File:")
+	line = strings.Trim(strings.TrimSpace(line), "`*#: ")
+	if line == "" || strings.ContainsAny(line, " \t") || !strings.Contains(line, ".") && !strings.Contains(line, "/") {
+		return ""
+	}
+	return line
+}
+
+// ApplyChanges writes the changes below root. Paths escaping root and hunks
+// whose context cannot be found are reported as conflicts and skipped.
+func ApplyChanges(root string, changes []FileChange, dryRun, backup bool) applyReport {
+	var report applyReport
+
+	for _, change := range changes {
+		target, err := resolveInside(root, change.Path)
+		if err != nil {
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s: %v", change.Path, err))
+			continue
+		}
+		target = longPath(target)
+
+		original, err := os.ReadFile(target)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s: %v", change.Path, err))
+			continue
+		}
+
+		action, updated, err := planChange(change, string(original), exists)
+		if err != nil {
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s: %v", change.Path, err))
+			continue
+		}
+
+		if dryRun {
+			report.Applied = append(report.Applied, fmt.Sprintf("would %s %s", action, change.Path))
+			continue
+		}
+
+		if backup && exists {
+			if err := os.WriteFile(target+".orig", original, 0644); err != nil {
+				report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s: writing backup: %v", change.Path, err))
+				continue
+			}
+		}
+
+		if change.Delete {
+			err = os.Remove(target)
+		} else {
+			err = writeFileCreatingDirs(target, updated)
+		}
+		if err != nil {
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s: %v", change.Path, err))
+			continue
+		}
+		report.Applied = append(report.Applied, fmt.Sprintf("%s %s", pastTense[action], change.Path))
+	}
+
+	return report
+}
+
+func planChange(change FileChange, original string, exists bool) (string, string, error) {
+	switch {
+	case change.Delete:
+		if !exists {
+			return "", "", errors.New("file to delete does not exist")
+		}
+		return "delete", "", nil
+	case !change.IsDiff:
+		if exists {
+			return "replace", change.Content, nil
+		}
+		return "create", change.Content, nil
+	}
+
+	updated, err := applyHunks(original, change.Hunks)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "create", updated, nil
+	}
+	return "update", updated, nil
+}
+
+// applyHunks applies hunks in order. Each hunk is searched for near its
+// declared position first, so line numbers that are slightly off still apply.
+func applyHunks(original string, hunks []Hunk) (string, error) {
+	lines := strings.Split(original, "\n")
+	if original == "" {
+		lines = nil
+	}
+	offset := 0
+
+	for n, hunk := range hunks {
+		if hunk.Incomplete {
+			return "", fmt.Errorf("hunk %d (line %d) does not have the lines its @@ header counts", n+1, hunk.OldStart)
+		}
+		var oldLines, newLines []string
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case ' ':
+				oldLines = append(oldLines, line[1:])
+				newLines = append(newLines, line[1:])
+			case '-':
+				oldLines = append(oldLines, line[1:])
+			case '+':
+				newLines = append(newLines, line[1:])
+			}
+		}
+
+		pos := findHunk(lines, oldLines, hunk.OldStart-1+offset)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d (line %d) does not match the current file", n+1, hunk.OldStart)
+		}
+
+		updated := append([]string{}, lines[:pos]...)
+		updated = append(updated, newLines...)
+		lines = append(updated, lines[pos+len(oldLines):]...)
+		offset += len(newLines) - len(oldLines)
+	}
+
+	updated := strings.Join(lines, "\n")
+	// A file created from /dev/null ends with a newline unless the diff
+	// says otherwise; existing files keep theirs through the split.
+	if original == "" && len(lines) > 0 && !hunks[len(hunks)-1].NoNewline {
+		updated += "\n"
+	}
+	return updated, nil
+}
+
+func findHunk(lines, oldLines []string, expected int) int {
+	if expected < 0 {
+		expected = 0
+	}
+	for distance := 0; distance <= len(lines); distance++ {
+		for _, pos := range []int{expected - distance, expected + distance} {
+			if pos >= 0 && pos+len(oldLines) <= len(lines) && linesEqual(lines[pos:pos+len(oldLines)], oldLines) {
+				return pos
+			}
+		}
+	}
+	return -1
+}
+
+func linesEqual(a, b []string) bool {
+	for i := range b {
+		if strings.TrimRight(a[i], " \t") != strings.TrimRight(b[i], " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveInside joins path to root and refuses results outside root,
+// also when a symlink below root points outside it.
+func resolveInside(root, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", errors.New("absolute paths are not allowed")
+	}
+	target := filepath.Join(root, filepath.FromSlash(path))
+	if !isInside(root, target) {
+		return "", errors.New("path escapes the target directory")
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	if !isInside(realRoot, real) {
+		return "", errors.New("path escapes the target directory through a symlink")
+	}
+	return target, nil
+}
+
+func isInside(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeFileCreatingDirs saves content through SaveOutput, so a failed
+// write leaves the original file intact.
+func writeFileCreatingDirs(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return SaveOutput(content, path)
+}
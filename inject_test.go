@@ -0,0 +1,115 @@
+// inject_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyDiffs(t *testing.T) {
+	cases := []struct {
+		name     string
+		original string
+		diff     string
+		want     string
+		conflict string
+	}{
+		{
+			name:     "removed SQL comment",
+			original: "-- old\nSELECT 1;\n",
+			diff:     "--- a/q.sql\n+++ b/q.sql\n@@ -1,2 +1,2 @@\n--- old\n+-- new\n SELECT 1;\n",
+			want:     "-- new\nSELECT 1;\n",
+		},
+		{
+			name:     "removed line that looks like a header",
+			original: "a\n-- x\n+++ y\nb\n",
+			diff:     "--- a/q.sql\n+++ b/q.sql\n@@ -1,4 +1,2 @@\n a\n--- x\n-+++ y\n b\n",
+			want:     "a\nb\n",
+		},
+		{
+			name:     "omitted counts",
+			original: "a\n",
+			diff:     "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n+b\n",
+			want:     "b\n",
+		},
+		{
+			name:     "cut short",
+			original: "a\nb\nc\n",
+			diff:     "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n",
+			conflict: "does not have the lines its @@ header counts",
+		},
+		{
+			name:     "context not found",
+			original: "a\n",
+			diff:     "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-z\n+b\n",
+			conflict: "does not match the current file",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			changes := ParseResponse("```diff\n" + tc.diff + "```\n")
+			if len(changes) != 1 {
+				t.Fatalf("parsed %d changes, want 1", len(changes))
+			}
+			target := filepath.Join(root, filepath.FromSlash(changes[0].Path))
+			if err := os.WriteFile(target, []byte(tc.original), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			report := ApplyChanges(root, changes, false, false)
+			data, _ := os.ReadFile(target)
+			if tc.conflict != "" {
+				if len(report.Conflicts) != 1 || !strings.Contains(report.Conflicts[0], tc.conflict) || len(report.Applied) != 0 {
+					t.Errorf("got applied %v, conflicts %v; want a conflict with %q", report.Applied, report.Conflicts, tc.conflict)
+				}
+				if string(data) != tc.original {
+					t.Errorf("conflicting diff changed the file to %q", data)
+				}
+				return
+			}
+			if len(report.Conflicts) != 0 {
+				t.Fatalf("conflicts: %v", report.Conflicts)
+			}
+			if string(data) != tc.want {
+				t.Errorf("got %q, want %q", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyRefusesSymlinksOutOfRoot(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "target.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "inner"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("inner", filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []FileChange{
+		{Path: "escape/new.txt", Content: "x\n"},
+		{Path: "escape/sub/new.txt", Content: "x\n"},
+		{Path: "link.txt", Content: "x\n"},
+		{Path: "alias/ok.txt", Content: "ok\n"},
+	}
+	report := ApplyChanges(root, changes, false, false)
+	if len(report.Conflicts) != 3 || len(report.Applied) != 1 {
+		t.Errorf("got applied %v, conflicts %v; want only alias/ok.txt applied", report.Applied, report.Conflicts)
+	}
+	entries, _ := os.ReadDir(outside)
+	if len(entries) != 0 {
+		t.Errorf("files written outside the root: %v", entries)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "inner", "ok.txt")); string(data) != "ok\n" {
+		t.Errorf("symlink inside the root not followed: %q", data)
+	}
+}
@@ -0,0 +1,67 @@
+// language.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+var languagesByExt = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "jsx",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".java":  "java",
+	".kt":    "kotlin",
+	".swift": "swift",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".php":   "php",
+	".scala": "scala",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "zsh",
+	".ps1":   "powershell",
+	".sql":   "sql",
+	".html":  "html",
+	".css":   "css",
+	".scss":  "scss",
+	".vue":   "vue",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".xml":   "xml",
+	".md":    "markdown",
+	".proto": "protobuf",
+	".tf":    "hcl",
+	".lua":   "lua",
+	".dart":  "dart",
+}
+
+var languagesByName = map[string]string{
+	"Dockerfile":  "dockerfile",
+	"Makefile":    "makefile",
+	"Jenkinsfile": "groovy",
+	"go.mod":      "go-mod",
+	"go.sum":      "go-sum",
+}
+
+// detectLanguage names the language of a file from its name or extension,
+// using the identifiers common to Markdown code fences.
+func detectLanguage(path string) string {
+	base := filepath.Base(strings.SplitN(path, "#", 2)[0])
+	if language, ok := languagesByName[base]; ok {
+		return language
+	}
+	return languagesByExt[strings.ToLower(filepath.Ext(base))]
+}
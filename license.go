@@ -0,0 +1,133 @@
+// license.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type commentSyntax struct {
+	line       string
+	blockStart string
+	blockEnd   string
+}
+
+var (
+	cStyleComments     = commentSyntax{"//", "/*", "*/"}
+	hashComments       = commentSyntax{line: "#"}
+	markupComments     = commentSyntax{blockStart: "<!--", blockEnd: "-->"}
+	commentsByLanguage = map[string]commentSyntax{
+		"go": cStyleComments, "c": cStyleComments, "cpp": cStyleComments, "csharp": cStyleComments,
+		"java": cStyleComments, "kotlin": cStyleComments, "scala": cStyleComments, "swift": cStyleComments,
+		"javascript": cStyleComments, "jsx": cStyleComments, "typescript": cStyleComments, "tsx": cStyleComments,
+		"rust": cStyleComments, "dart": cStyleComments, "php": cStyleComments, "protobuf": cStyleComments,
+		"groovy": cStyleComments, "css": {blockStart: "/*", blockEnd: "*/"}, "scss": cStyleComments,
+		"python": hashComments, "ruby": hashComments, "bash": hashComments, "zsh": hashComments,
+		"yaml": hashComments, "toml": hashComments, "makefile": hashComments, "dockerfile": hashComments,
+		"hcl":        {"#", "/*", "*/"},
+		"powershell": {"#", "<#", "#>"},
+		"sql":        {"--", "/*", "*/"},
+		"lua":        {"--", "--[[", "]]"},
+		"html":       markupComments, "xml": markupComments, "vue": markupComments, "markdown": markupComments,
+	}
+)
+
+var (
+	licenseMarkerRe = regexp.MustCompile(`(?i)copyright|licen[cs]e|spdx-license-identifier|all rights reserved`)
+	licenseYearsRe  = regexp.MustCompile(`\b(19|20)\d\d(\s*[-,]\s*(19|20)\d\d)*\b`)
+)
+
+// dedupeLicenseHeaders keeps the first copy of every license header and
+// replaces later copies with a one-line note. Headers match when they only
+// differ in comment syntax, whitespace or years.
+func dedupeLicenseHeaders(results []FileResult, config *Config) []FileResult {
+	firstSeen := make(map[string]string)
+	omitted := 0
+
+	for i, result := range results {
+		syntax, ok := commentsByLanguage[result.Language]
+		if !ok {
+			continue
+		}
+		start, end := leadingComment(result.Content, syntax)
+		header := result.Content[start:end]
+		if !licenseMarkerRe.MatchString(header) {
+			continue
+		}
+
+		key := normalizeLicenseHeader(header, syntax)
+		first, seen := firstSeen[key]
+		if !seen {
+			firstSeen[key] = result.Path
+			continue
+		}
+
+		note := fmt.Sprintf("license header omitted, same as in %s", first)
+		if syntax.line != "" {
+			note = syntax.line + " (" + note + ")"
+		} else {
+			note = syntax.blockStart + " " + note + " " + syntax.blockEnd
+		}
+		results[i].Content = result.Content[:start] + note + "\n" + result.Content[end:]
+		results[i].TokenCount = estimateTokens(results[i].Content, config.Tokenizer)
+		omitted++
+	}
+
+	config.Debugf("Omitted %d repeated license headers", omitted)
+	return results
+}
+
+// leadingComment returns the byte range of the comment block at the top of
+// content, after an optional shebang line. The range ends after the
+// block's last line break.
+func leadingComment(content string, syntax commentSyntax) (int, int) {
+	lines := strings.SplitAfter(content, "\n")
+	start, i := 0, 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		start, i = len(lines[0]), 1
+	}
+
+	end := start
+	if i < len(lines) && syntax.blockStart != "" && strings.HasPrefix(strings.TrimSpace(lines[i]), syntax.blockStart) {
+		rest := strings.TrimSpace(lines[i])[len(syntax.blockStart):]
+		for {
+			end += len(lines[i])
+			i++
+			if strings.Contains(rest, syntax.blockEnd) {
+				return start, end
+			}
+			if i >= len(lines) {
+				// An unterminated block is not a header.
+				return start, start
+			}
+			rest = lines[i]
+		}
+	}
+
+	for ; syntax.line != "" && i < len(lines); i++ {
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), syntax.line) {
+			break
+		}
+		end += len(lines[i])
+	}
+	return start, end
+}
+
+func normalizeLicenseHeader(header string, syntax commentSyntax) string {
+	var words []string
+	for _, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		for _, marker := range []string{syntax.blockStart, syntax.line} {
+			if marker != "" {
+				line = strings.TrimPrefix(line, marker)
+			}
+		}
+		if syntax.blockEnd != "" {
+			line = strings.TrimSuffix(line, syntax.blockEnd)
+		}
+		line = strings.TrimLeft(line, "*#-/! ")
+		words = append(words, strings.Fields(licenseYearsRe.ReplaceAllString(line, "YEAR"))...)
+	}
+	return strings.Join(words, " ")
+}
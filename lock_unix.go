@@ -0,0 +1,43 @@
+// lock_unix.go
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// lockDir takes an exclusive lock on the lock file next to dir. The lock lasts
+// until the returned file is closed or the process exits.
+func lockDir(dir string) (*os.File, error) {
+	file, err := os.OpenFile(dir+tempLockSuffix, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// dirLocked reports whether a live process holds the lock of dir.
+func dirLocked(dir string) bool {
+	file, err := os.Open(dir + tempLockSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		// Unreadable: leave it alone.
+		return true
+	}
+	defer file.Close()
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return true
+	}
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return false
+}
@@ -0,0 +1,72 @@
+// logger.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Logger serializes debug lines so concurrent runs (batch, crawl) never
+// interleave them. A Logger without a writer keeps the lines in memory,
+// which lets library callers show them instead of having them on stdout.
+type Logger struct {
+	mu      sync.Mutex
+	out     io.Writer
+	entries []string
+}
+
+var defaultLogger = NewLogger(os.Stderr)
+
+func NewLogger(out io.Writer) *Logger {
+	return &Logger{out: out}
+}
+
+// NewCaptureLogger returns a Logger that only records lines; read them back
+// with Entries.
+func NewCaptureLogger() *Logger {
+	return &Logger{}
+}
+
+func (l *Logger) Printf(format string, args ...interface{}) {
+	line := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.out == nil {
+		l.entries = append(l.entries, line)
+		return
+	}
+	fmt.Fprintln(l.out, line)
+}
+
+// Entries returns the captured lines.
+func (l *Logger) Entries() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.entries...)
+}
+
+// Debugf logs through the configured logger when debug output is enabled.
+func (config *Config) Debugf(format string, args ...interface{}) {
+	if !config.Debug {
+		return
+	}
+	config.logger().Printf("DEBUG"+config.runTag()+": "+format, args...)
+}
+
+func (config *Config) runTag() string {
+	if config.RunID == "" {
+		return ""
+	}
+	return " [" + config.RunID + "]"
+}
+
+func (config *Config) logger() *Logger {
+	if config.Logger == nil {
+		return defaultLogger
+	}
+	return config.Logger
+}
@@ -2,38 +2,157 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"time"
 )
 
+var commands = map[string]func(args []string) error{
+	"plan":    runPlan,
+	"apply":   runApply,
+	"session": runSession,
+	"models":  runModels,
+	"batch":   runBatch,
+	"crawl":   runCrawl,
+	"restore": runRestore,
+	"config":  runConfig,
+	"setup":   runSetup,
+	"history": runHistory,
+	"admin":   runAdmin,
+}
+
 func main() {
-	config := ParseFlags()
+	removeTempDirsOnInterrupt()
 
-	if config.Debug {
-		fmt.Println("Debug mode enabled")
-		fmt.Printf("Configuration: %+v\n", config)
+	args, err := expandAlias(os.Args[1:])
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
 	}
 
-	results, err := ProcessFiles(config)
+	if len(args) > 0 {
+		if command, ok := commands[args[0]]; ok {
+			if err := command(args[1:]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	config, err := ParseFlags(flag.CommandLine, args)
 	if err != nil {
-		fmt.Println("Error processing files:", err)
+		fmt.Println("Error loading configuration:", err)
 		os.Exit(1)
 	}
 
-	output := GenerateOutput(results, config)
+	start := time.Now()
+	config.started = start
+	config.Debugf("Debug mode enabled")
+	config.Debugf("Configuration: %+v", config)
+
+	if config.SaveConfig != "" {
+		if err := SaveConfigFile(config.SaveConfig, config); err != nil {
+			fmt.Println("Error saving configuration:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Configuration saved to", config.SaveConfig)
+		return
+	}
+
+	if err := ValidateConfig(config); err != nil {
+		fail(config, "Error:", err)
+	}
+
+	if err := resolveModel(config); err != nil {
+		fail(config, "Error resolving model:", err)
+	}
+
+	results, err := ProcessFiles(config)
+	if err != nil {
+		fail(config, "Error processing files:", err)
+	}
+
+	if config.Prune {
+		if results, err = pruneResults(results, config); err != nil {
+			fail(config, "Error pruning files:", err)
+		}
+	}
+
+	included := results
+	var deltaSections []Section
+	if config.Delta {
+		if results, deltaSections, err = deltaResults(results, config); err != nil {
+			fail(config, "Error comparing with the previous run:", err)
+		}
+	}
+
+	sections, err := BuildSections(results, config)
+	if err != nil {
+		fail(config, "Error building sections:", err)
+	}
+	sections = append(deltaSections, sections...)
+
+	if err := EmitChunks(results, sections, config); err != nil {
+		fail(config, "Error writing output:", err)
+	}
+
+	if config.Delta {
+		recordManifest(included, config)
+	}
+
+	if config.SuggestIgnores {
+		reportSuggestions(results, config, os.Stderr)
+	}
+	recordHistory(results, start, config)
+
+	if warnings := config.Warnings.List(); len(warnings) > 0 {
+		fmt.Fprintf(os.Stderr, "%d warning(s), see above\n", len(warnings))
+	}
+}
+
+// fail reports an error that ends the run. With --format json-result it
+// is a failed RunResult on stdout, so scripts always get one to parse.
+func fail(config *Config, message string, err error) {
+	if config.Format == "json-result" {
+		fmt.Print(newRunResult(nil, config, err))
+	} else {
+		fmt.Println(message, err)
+	}
+	os.Exit(1)
+}
 
+func EmitOutput(output string, config *Config) error {
 	if config.Save {
-		err = SaveOutput(output, config.OutputFile)
+		changed, err := true, error(nil)
+		if config.tail != nil {
+			err = config.tail.finish(output, config.OutputFile)
+		} else {
+			changed, err = saveIfChanged(output, config.OutputFile, config)
+		}
 		if err != nil {
-			fmt.Println("Error saving output:", err)
-			os.Exit(1)
+			return err
+		}
+		if changed {
+			fmt.Println("Output saved to", config.OutputFile)
+		} else {
+			fmt.Println("Output unchanged:", config.OutputFile)
 		}
-		fmt.Println("Output saved to", config.OutputFile)
 	} else {
 		fmt.Println(output)
 	}
 
+	if err := DeliverSinks(output, config); err != nil {
+		return err
+	}
+
 	if config.ShowSize {
-		fmt.Printf("Total size: %d bytes\n", len(output))
+		size := formatSize(int64(len(output)))
+		if len(output) >= 1<<10 {
+			size += fmt.Sprintf(" (%d bytes)", len(output))
+		}
+		fmt.Println("Total size:", size)
 	}
+	return nil
 }
@@ -0,0 +1,776 @@
+// migrations.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sqlIdent matches a possibly quoted and schema-qualified SQL name.
+const sqlIdent = "((?:\"[^\"]+\"|`[^`]+`|\\[[^\\]]+\\]|[\\w$]+)(?:\\.(?:\"[^\"]+\"|`[^`]+`|[\\w$]+))?)"
+
+var (
+	migrateFileRe     = regexp.MustCompile(`^\d+_.+\.(up|down)\.sql$`)
+	numberedSQLRe     = regexp.MustCompile(`^[Vv]?\d+.*\.sql$`)
+	railsMigrationRe  = regexp.MustCompile(`^\d+_\w+\.rb$`)
+	gooseMarkerRe     = regexp.MustCompile(`(?im)^--\s*\+goose\s+(up|down)\b`)
+	migrationNumberRe = regexp.MustCompile(`^[Vv]?(\d+)`)
+
+	createTableRe = regexp.MustCompile(`(?is)^create\s+(?:unlogged\s+)?table\s+(?:if\s+not\s+exists\s+)?` + sqlIdent + `\s*\((.*)\)\s*(.*)$`)
+	alterTableRe  = regexp.MustCompile(`(?is)^alter\s+table\s+(?:if\s+exists\s+)?(?:only\s+)?` + sqlIdent + `\s+(.*)$`)
+	dropTableRe   = regexp.MustCompile(`(?is)^drop\s+table\s+(?:if\s+exists\s+)?(.*?)(?:\s+(?:cascade|restrict))?$`)
+	createIndexRe = regexp.MustCompile(`(?is)^create\s+(?:unique\s+)?index\s+(?:concurrently\s+)?(?:if\s+not\s+exists\s+)?` + sqlIdent + `\s+on\s+(?:only\s+)?` + sqlIdent)
+	dropIndexRe   = regexp.MustCompile(`(?is)^drop\s+index\s+(?:concurrently\s+)?(?:if\s+exists\s+)?` + sqlIdent)
+	createOtherRe = regexp.MustCompile(`(?is)^create\s+(?:or\s+replace\s+)?(?:materialized\s+)?(type|view|function|procedure|trigger|sequence|extension|schema|domain)\s+(?:if\s+not\s+exists\s+)?` + sqlIdent)
+	dropOtherRe   = regexp.MustCompile(`(?is)^drop\s+(?:materialized\s+)?(type|view|function|procedure|trigger|sequence|extension|schema|domain)\s+(?:if\s+exists\s+)?` + sqlIdent)
+	dataStmtRe    = regexp.MustCompile(`(?i)^(insert|update|delete|select|begin|commit|rollback|start\s+transaction|set|pragma|analyze|vacuum|copy|lock|truncate)\b`)
+
+	addActionRe        = regexp.MustCompile(`(?is)^add\s+(?:column\s+)?(?:if\s+not\s+exists\s+)?(.*)$`)
+	dropConstraintRe   = regexp.MustCompile(`(?is)^drop\s+constraint\s+(?:if\s+exists\s+)?` + sqlIdent)
+	dropColumnRe       = regexp.MustCompile(`(?is)^drop\s+(?:column\s+)?(?:if\s+exists\s+)?` + sqlIdent + `(?:\s+(?:cascade|restrict))?$`)
+	renameTableRe      = regexp.MustCompile(`(?is)^rename\s+to\s+` + sqlIdent + `$`)
+	renameColumnRe     = regexp.MustCompile(`(?is)^rename\s+(?:column\s+)?` + sqlIdent + `\s+to\s+` + sqlIdent + `$`)
+	alterColumnTypeRe  = regexp.MustCompile(`(?is)^alter\s+(?:column\s+)?` + sqlIdent + `\s+(?:set\s+data\s+)?type\s+(.*?)(?:\s+using\s+.*)?$`)
+	alterColumnSetRe   = regexp.MustCompile(`(?is)^alter\s+(?:column\s+)?` + sqlIdent + `\s+(set|drop)\s+(not\s+null|default)\s*(.*)$`)
+	modifyColumnRe     = regexp.MustCompile(`(?is)^modify\s+(?:column\s+)?(.*)$`)
+	changeColumnRe     = regexp.MustCompile(`(?is)^change\s+(?:column\s+)?` + sqlIdent + `\s+(.*)$`)
+	columnClauseRe     = regexp.MustCompile(`(?i)\s+(not\s+null|null|default|primary\s+key|references|unique|check|constraint|generated|collate|auto_increment)\b`)
+	constraintPrefixRe = regexp.MustCompile(`(?i)^(constraint|primary\s+key|unique|foreign\s+key|check|key|index|exclude)\b`)
+
+	railsCreateTableRe = regexp.MustCompile(`^(\s*)create_table\s+[:"']?(\w+)["']?(.*)`)
+	railsColumnRe      = regexp.MustCompile(`^\s*t\.(\w+)\s+[:"'](\w+)["']?(.*)`)
+	railsCallRe        = regexp.MustCompile(`^\s*(add_column|remove_column|rename_column|change_column|drop_table|rename_table|add_index|add_reference|add_belongs_to|remove_reference)\s*\(?\s*(.*?)\)?\s*$`)
+	railsArgRe         = regexp.MustCompile(`^[:"']?(\w+)["']?$`)
+	railsDefDownRe     = regexp.MustCompile(`^(\s*)def\s+(down|self\.down)\b`)
+)
+
+// migrationKind names the migration tool a file belongs to ("goose",
+// "golang-migrate", "prisma", "rails" or "sql" for numbered files in a
+// migrations directory), or "" for other files.
+func migrationKind(result FileResult) string {
+	slashed := filepath.ToSlash(result.Path)
+	base := path.Base(slashed)
+	dir := path.Base(path.Dir(slashed))
+	switch {
+	case base == "migration.sql" && strings.Contains(slashed, "prisma/migrations/"):
+		return "prisma"
+	case strings.HasSuffix(path.Dir(slashed), "db/migrate") && railsMigrationRe.MatchString(base):
+		return "rails"
+	case !strings.HasSuffix(base, ".sql"):
+		return ""
+	case gooseMarkerRe.MatchString(result.Content):
+		return "goose"
+	case migrateFileRe.MatchString(base):
+		return "golang-migrate"
+	case (dir == "migrations" || dir == "migration" || dir == "migrate") && numberedSQLRe.MatchString(base):
+		return "sql"
+	}
+	return ""
+}
+
+// migrationDir is the directory a group of migrations is consolidated for;
+// Prisma keeps each migration in its own subdirectory.
+func migrationDir(kind, p string) string {
+	if kind == "prisma" {
+		return filepath.Dir(filepath.Dir(p))
+	}
+	return filepath.Dir(p)
+}
+
+// migrationVersion is the name migrations are ordered by.
+func migrationVersion(kind, p string) string {
+	if kind == "prisma" {
+		return filepath.Base(filepath.Dir(p))
+	}
+	return filepath.Base(p)
+}
+
+// lessMigration orders migration names by their leading number, so 2_x
+// comes before 10_x, and by name otherwise.
+func lessMigration(a, b string) bool {
+	na, nb := migrationNumberRe.FindStringSubmatch(a), migrationNumberRe.FindStringSubmatch(b)
+	if na != nil && nb != nil {
+		x, y := strings.TrimLeft(na[1], "0"), strings.TrimLeft(nb[1], "0")
+		if len(x) != len(y) {
+			return len(x) < len(y)
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return a < b
+}
+
+// consolidateMigrations replaces each directory of schema migrations with
+// one file holding the schema they produce. Rails and Prisma projects that
+// keep a schema file (db/schema.rb, db/structure.sql, schema.prisma) get
+// that file instead; otherwise the up migrations are replayed in order:
+// tables are created, altered, renamed and dropped, indexes and other
+// objects follow their CREATE and DROP statements, and data statements and
+// down migrations are left out.
+func consolidateMigrations(results []FileResult, config *Config) []FileResult {
+	type group struct {
+		kind, dir, source string
+		first             int
+		members           []FileResult
+	}
+	groups := make(map[string]*group)
+	var order []string
+	present := make(map[string]bool)
+	for i, result := range results {
+		present[filepath.Clean(result.Path)] = true
+		kind := migrationKind(result)
+		if kind == "" {
+			continue
+		}
+		dir := migrationDir(kind, result.Path)
+		key := kind + "\x00" + dir
+		g, ok := groups[key]
+		if !ok {
+			g = &group{kind: kind, dir: dir, source: result.Source, first: i}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.members = append(g.members, result)
+	}
+	if len(groups) == 0 {
+		return results
+	}
+
+	replacements := make(map[int][]FileResult)
+	dropped := make(map[string]bool)
+	for _, key := range order {
+		g := groups[key]
+		sort.SliceStable(g.members, func(i, j int) bool {
+			return lessMigration(migrationVersion(g.kind, g.members[i].Path), migrationVersion(g.kind, g.members[j].Path))
+		})
+		for _, member := range g.members {
+			dropped[member.Path] = true
+		}
+
+		if schemaFile := existingSchemaFile(g.kind, g.dir, present); schemaFile != "" {
+			config.Debugf("Replacing %d %s migrations in %s with %s", len(g.members), g.kind, g.dir, schemaFile)
+			continue
+		}
+
+		schema := newSQLSchema()
+		var modTime time.Time
+		applied := 0
+		for _, member := range g.members {
+			switch g.kind {
+			case "rails":
+				schema.applyRails(member.Content)
+			case "goose":
+				schema.applySQL(gooseUp(member.Content))
+			case "golang-migrate":
+				if !strings.HasSuffix(member.Path, ".up.sql") {
+					continue
+				}
+				schema.applySQL(member.Content)
+			default:
+				schema.applySQL(member.Content)
+			}
+			applied++
+			if member.ModTime.After(modTime) {
+				modTime = member.ModTime
+			}
+		}
+		last := migrationVersion(g.kind, g.members[len(g.members)-1].Path)
+		header := fmt.Sprintf("-- Schema consolidated from %d %s migrations in %s (latest: %s).\n", applied, g.kind, filepath.ToSlash(g.dir), last)
+		if g.kind == "rails" {
+			header += "-- Column types are Rails types.\n"
+		}
+		content := header + "\n" + schema.String()
+		path := filepath.Join(g.dir, "consolidated_schema.sql")
+		config.Debugf("Consolidated %d %s migrations in %s", applied, g.kind, g.dir)
+		replacements[g.first] = append(replacements[g.first], newFileResult(path, walkedPath(path, config.Dirs), g.source, []byte(content), modTime, config))
+	}
+
+	consolidated := make([]FileResult, 0, len(results))
+	for i, result := range results {
+		consolidated = append(consolidated, replacements[i]...)
+		if !dropped[result.Path] {
+			consolidated = append(consolidated, result)
+		}
+	}
+	return consolidated
+}
+
+// existingSchemaFile returns the schema file a Rails or Prisma project keeps
+// next to its migrations, or "". Only files among the results count, so an
+// ignored or filtered schema file falls back to replaying the migrations.
+func existingSchemaFile(kind, dir string, present map[string]bool) string {
+	var candidates []string
+	switch kind {
+	case "rails":
+		db := filepath.Dir(dir)
+		candidates = []string{filepath.Join(db, "schema.rb"), filepath.Join(db, "structure.sql")}
+	case "prisma":
+		candidates = []string{filepath.Join(filepath.Dir(dir), "schema.prisma")}
+	}
+	for _, candidate := range candidates {
+		if present[filepath.Clean(candidate)] {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// gooseUp returns the Up part of a goose migration.
+func gooseUp(content string) string {
+	var buffer strings.Builder
+	up := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if m := gooseMarkerRe.FindStringSubmatch(line); m != nil {
+			up = strings.EqualFold(m[1], "up")
+			continue
+		}
+		if up {
+			buffer.WriteString(line)
+		}
+	}
+	return buffer.String()
+}
+
+// sqlSchema is the state of a database schema replayed from migrations.
+type sqlSchema struct {
+	// order lists the object keys in creation order.
+	order   []string
+	tables  map[string]*sqlTable
+	objects map[string]schemaObject
+	counter int
+}
+
+type sqlTable struct {
+	name        string
+	columns     []sqlColumn
+	constraints []string
+	suffix      string
+	// extra holds ALTER statements that could not be folded into the table.
+	extra []string
+}
+
+type sqlColumn struct {
+	name, def string
+}
+
+// schemaObject is an index, view, type or other statement, kept verbatim.
+type schemaObject struct {
+	statement string
+	table     string
+}
+
+func newSQLSchema() *sqlSchema {
+	return &sqlSchema{tables: make(map[string]*sqlTable), objects: make(map[string]schemaObject)}
+}
+
+// sqlKey normalizes a name for lookups: quotes removed, lower case.
+func sqlKey(name string) string {
+	return strings.ToLower(strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(name))
+}
+
+func (s *sqlSchema) add(key string) {
+	for _, existing := range s.order {
+		if existing == key {
+			return
+		}
+	}
+	s.order = append(s.order, key)
+}
+
+func (s *sqlSchema) remove(key string) {
+	for i, existing := range s.order {
+		if existing == key {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	delete(s.tables, key)
+	delete(s.objects, key)
+}
+
+// applySQL replays the statements of one migration.
+func (s *sqlSchema) applySQL(content string) {
+	for _, stmt := range splitSQL(content) {
+		s.applyStatement(stmt)
+	}
+}
+
+func (s *sqlSchema) applyStatement(stmt string) {
+	switch {
+	case dataStmtRe.MatchString(stmt):
+	case createTableRe.MatchString(stmt):
+		m := createTableRe.FindStringSubmatch(stmt)
+		table := &sqlTable{name: m[1], suffix: strings.TrimSpace(m[3])}
+		for _, item := range splitTopLevel(m[2], ',') {
+			table.addItem(item)
+		}
+		key := "table:" + sqlKey(m[1])
+		s.remove(key)
+		s.tables[key] = table
+		s.add(key)
+	case alterTableRe.MatchString(stmt):
+		m := alterTableRe.FindStringSubmatch(stmt)
+		key := "table:" + sqlKey(m[1])
+		table, ok := s.tables[key]
+		if !ok {
+			s.addObject("", stmt, "")
+			return
+		}
+		for _, action := range splitTopLevel(m[2], ',') {
+			if newName, ok := table.alter(action); ok && newName != "" {
+				newKey := "table:" + sqlKey(newName)
+				for i, existing := range s.order {
+					if existing == key {
+						s.order[i] = newKey
+					}
+				}
+				delete(s.tables, key)
+				s.tables[newKey] = table
+				// Indexes move with the table.
+				for objectKey, object := range s.objects {
+					if object.table != key {
+						continue
+					}
+					object.table = newKey
+					if loc := createIndexRe.FindStringSubmatchIndex(object.statement); loc != nil {
+						object.statement = object.statement[:loc[4]] + newName + object.statement[loc[5]:]
+					}
+					s.objects[objectKey] = object
+				}
+				key = newKey
+			} else if !ok {
+				table.extra = append(table.extra, fmt.Sprintf("ALTER TABLE %s %s;", table.name, action))
+			}
+		}
+	case dropTableRe.MatchString(stmt):
+		for _, name := range splitTopLevel(dropTableRe.FindStringSubmatch(stmt)[1], ',') {
+			key := "table:" + sqlKey(name)
+			s.remove(key)
+			for objectKey, object := range s.objects {
+				if object.table == key {
+					s.remove(objectKey)
+				}
+			}
+		}
+	case createIndexRe.MatchString(stmt):
+		m := createIndexRe.FindStringSubmatch(stmt)
+		s.addObject("index:"+sqlKey(m[1]), stmt, "table:"+sqlKey(m[2]))
+	case dropIndexRe.MatchString(stmt):
+		s.remove("index:" + sqlKey(dropIndexRe.FindStringSubmatch(stmt)[1]))
+	case createOtherRe.MatchString(stmt):
+		m := createOtherRe.FindStringSubmatch(stmt)
+		s.addObject(strings.ToLower(m[1])+":"+sqlKey(m[2]), stmt, "")
+	case dropOtherRe.MatchString(stmt):
+		m := dropOtherRe.FindStringSubmatch(stmt)
+		s.remove(strings.ToLower(m[1]) + ":" + sqlKey(m[2]))
+	default:
+		s.addObject("", stmt, "")
+	}
+}
+
+// addObject keeps a statement; an empty key keeps it unconditionally, a
+// repeated key replaces the earlier statement in place.
+func (s *sqlSchema) addObject(key, stmt, table string) {
+	if key == "" {
+		s.counter++
+		key = fmt.Sprintf("stmt:%d", s.counter)
+	}
+	s.objects[key] = schemaObject{statement: stmt, table: table}
+	s.add(key)
+}
+
+func (t *sqlTable) addItem(item string) {
+	item = strings.TrimSpace(item)
+	if item == "" {
+		return
+	}
+	if constraintPrefixRe.MatchString(item) {
+		t.constraints = append(t.constraints, item)
+		return
+	}
+	name, def, _ := strings.Cut(item, " ")
+	t.setColumn(name, strings.TrimSpace(def))
+}
+
+func (t *sqlTable) column(name string) int {
+	for i, column := range t.columns {
+		if sqlKey(column.name) == sqlKey(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *sqlTable) setColumn(name, def string) {
+	if i := t.column(name); i >= 0 {
+		t.columns[i].def = def
+		return
+	}
+	t.columns = append(t.columns, sqlColumn{name: name, def: def})
+}
+
+// alter folds one ALTER TABLE action into the table. It reports the new
+// table name for RENAME TO, and false for actions it cannot fold.
+func (t *sqlTable) alter(action string) (string, bool) {
+	action = strings.TrimSpace(action)
+	switch {
+	case addActionRe.MatchString(action):
+		t.addItem(addActionRe.FindStringSubmatch(action)[1])
+	case dropConstraintRe.MatchString(action):
+		name := sqlKey(dropConstraintRe.FindStringSubmatch(action)[1])
+		for i, constraint := range t.constraints {
+			if fields := strings.Fields(constraint); len(fields) > 1 && strings.EqualFold(fields[0], "constraint") && sqlKey(fields[1]) == name {
+				t.constraints = append(t.constraints[:i], t.constraints[i+1:]...)
+				break
+			}
+		}
+	case dropColumnRe.MatchString(action):
+		if i := t.column(dropColumnRe.FindStringSubmatch(action)[1]); i >= 0 {
+			t.columns = append(t.columns[:i], t.columns[i+1:]...)
+		}
+	case renameTableRe.MatchString(action):
+		t.name = renameTableRe.FindStringSubmatch(action)[1]
+		return t.name, true
+	case renameColumnRe.MatchString(action):
+		m := renameColumnRe.FindStringSubmatch(action)
+		if i := t.column(m[1]); i >= 0 {
+			t.columns[i].name = m[2]
+		}
+	case alterColumnTypeRe.MatchString(action):
+		m := alterColumnTypeRe.FindStringSubmatch(action)
+		i := t.column(m[1])
+		if i < 0 {
+			return "", false
+		}
+		_, rest := splitColumnType(t.columns[i].def)
+		t.columns[i].def = strings.TrimSpace(m[2] + rest)
+	case alterColumnSetRe.MatchString(action):
+		m := alterColumnSetRe.FindStringSubmatch(action)
+		i := t.column(m[1])
+		if i < 0 {
+			return "", false
+		}
+		clause := strings.ToUpper(strings.Join(strings.Fields(m[3]), " "))
+		def := removeColumnClause(t.columns[i].def, clause)
+		if strings.EqualFold(m[2], "set") {
+			if clause == "NOT NULL" {
+				def += " NOT NULL"
+			} else {
+				def += " DEFAULT " + strings.TrimSpace(m[4])
+			}
+		}
+		t.columns[i].def = def
+	case modifyColumnRe.MatchString(action):
+		t.addItem(modifyColumnRe.FindStringSubmatch(action)[1])
+	case changeColumnRe.MatchString(action):
+		m := changeColumnRe.FindStringSubmatch(action)
+		i := t.column(m[1])
+		if i < 0 {
+			return "", false
+		}
+		name, def, _ := strings.Cut(strings.TrimSpace(m[2]), " ")
+		t.columns[i] = sqlColumn{name: name, def: strings.TrimSpace(def)}
+	default:
+		return "", false
+	}
+	return "", true
+}
+
+// splitColumnType splits a column definition into its type and the clauses
+// after it.
+func splitColumnType(def string) (string, string) {
+	if loc := columnClauseRe.FindStringIndex(def); loc != nil {
+		return def[:loc[0]], def[loc[0]:]
+	}
+	return def, ""
+}
+
+// removeColumnClause removes NOT NULL or DEFAULT <expr> from a column
+// definition.
+func removeColumnClause(def, clause string) string {
+	locs := columnClauseRe.FindAllStringSubmatchIndex(def, -1)
+	for i, loc := range locs {
+		if strings.ToUpper(strings.Join(strings.Fields(def[loc[2]:loc[3]]), " ")) != clause {
+			continue
+		}
+		end := len(def)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		if clause == "NOT NULL" {
+			end = loc[1]
+		}
+		return def[:loc[0]] + def[end:]
+	}
+	return def
+}
+
+// applyRails replays the schema statements of a Rails migration, skipping
+// its down method.
+func (s *sqlSchema) applyRails(content string) {
+	var table *sqlTable
+	tableIndent, downIndent := "", ""
+	skipping := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if skipping {
+			if trimmed == "end" && indent == downIndent {
+				skipping = false
+			}
+			continue
+		}
+		if m := railsDefDownRe.FindStringSubmatch(line); m != nil {
+			skipping, downIndent = true, m[1]
+			continue
+		}
+
+		if table != nil {
+			if trimmed == "end" && indent == tableIndent {
+				table = nil
+				continue
+			}
+			if m := railsColumnRe.FindStringSubmatch(line); m != nil {
+				railsColumn(table, m[1], m[2], strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(m[3]), ",")))
+			} else if strings.HasPrefix(trimmed, "t.timestamps") {
+				table.setColumn("created_at", "datetime, null: false")
+				table.setColumn("updated_at", "datetime, null: false")
+			}
+			continue
+		}
+
+		if m := railsCreateTableRe.FindStringSubmatch(line); m != nil {
+			table = &sqlTable{name: m[2]}
+			if !strings.Contains(m[3], "id: false") {
+				table.setColumn("id", "primary_key")
+			}
+			key := "table:" + sqlKey(m[2])
+			s.remove(key)
+			s.tables[key] = table
+			s.add(key)
+			tableIndent = m[1]
+			if !strings.Contains(m[3], " do") {
+				table = nil
+			}
+			continue
+		}
+		if m := railsCallRe.FindStringSubmatch(line); m != nil {
+			s.applyRailsCall(m[1], m[2], trimmed)
+		}
+	}
+}
+
+// railsColumn adds a column declared as t.<kind> :name, options.
+func railsColumn(table *sqlTable, kind, name, options string) {
+	def := kind
+	if options != "" {
+		def += ", " + options
+	}
+	switch kind {
+	case "references", "belongs_to":
+		table.setColumn(name+"_id", "bigint"+strings.TrimPrefix(def, kind))
+	case "index":
+		table.constraints = append(table.constraints, "index "+name+strings.TrimPrefix(def, kind))
+	default:
+		table.setColumn(name, def)
+	}
+}
+
+func (s *sqlSchema) applyRailsCall(call, args, line string) {
+	var names []string
+	parts := splitTopLevel(args, ',')
+	for _, part := range parts {
+		if m := railsArgRe.FindStringSubmatch(strings.TrimSpace(part)); m != nil {
+			names = append(names, m[1])
+		} else {
+			break
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	key := "table:" + sqlKey(names[0])
+	table := s.tables[key]
+	options := ""
+	if len(parts) > len(names) {
+		options = strings.TrimSpace(strings.Join(parts[len(names):], ","))
+	}
+
+	switch {
+	case call == "drop_table":
+		s.remove(key)
+	case call == "rename_table" && len(names) == 2 && table != nil:
+		table.name = names[1]
+		newKey := "table:" + sqlKey(names[1])
+		for i, existing := range s.order {
+			if existing == key {
+				s.order[i] = newKey
+			}
+		}
+		delete(s.tables, key)
+		s.tables[newKey] = table
+	case table == nil:
+		s.addObject("", line, "")
+	case call == "add_column" && len(names) == 3, call == "change_column" && len(names) == 3:
+		railsColumn(table, names[2], names[1], options)
+	case call == "remove_column" && len(names) >= 2:
+		if i := table.column(names[1]); i >= 0 {
+			table.columns = append(table.columns[:i], table.columns[i+1:]...)
+		}
+	case call == "rename_column" && len(names) == 3:
+		if i := table.column(names[1]); i >= 0 {
+			table.columns[i].name = names[2]
+		}
+	case (call == "add_reference" || call == "add_belongs_to") && len(names) == 2:
+		railsColumn(table, "references", names[1], options)
+	case call == "remove_reference" && len(names) == 2:
+		if i := table.column(names[1] + "_id"); i >= 0 {
+			table.columns = append(table.columns[:i], table.columns[i+1:]...)
+		}
+	case call == "add_index":
+		table.constraints = append(table.constraints, "index "+strings.Join(parts[1:], ", "))
+	default:
+		table.extra = append(table.extra, line)
+	}
+}
+
+// String renders the schema as CREATE statements.
+func (s *sqlSchema) String() string {
+	var buffer strings.Builder
+	for i, key := range s.order {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		if table, ok := s.tables[key]; ok {
+			buffer.WriteString("CREATE TABLE " + table.name + " (\n")
+			var items []string
+			for _, column := range table.columns {
+				items = append(items, "  "+strings.TrimSpace(column.name+" "+column.def))
+			}
+			for _, constraint := range table.constraints {
+				items = append(items, "  "+constraint)
+			}
+			buffer.WriteString(strings.Join(items, ",\n"))
+			buffer.WriteString("\n)")
+			if table.suffix != "" {
+				buffer.WriteString(" " + table.suffix)
+			}
+			buffer.WriteString(";\n")
+			for _, extra := range table.extra {
+				buffer.WriteString(extra + "\n")
+			}
+		} else if object, ok := s.objects[key]; ok {
+			buffer.WriteString(object.statement + ";\n")
+		}
+	}
+	return buffer.String()
+}
+
+// splitSQL splits a script into statements, dropping comments. Quoted
+// strings, quoted names and dollar-quoted bodies are kept intact.
+func splitSQL(content string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '-' && strings.HasPrefix(content[i:], "--"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				i = len(content)
+			} else {
+				i += end
+				current.WriteByte('\n')
+			}
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+			} else {
+				i += end + 3
+			}
+			current.WriteByte(' ')
+		case c == '\'' || c == '"' || c == '`':
+			end := len(content) - 1
+			if j := strings.IndexByte(content[i+1:], c); j >= 0 {
+				end = i + 1 + j
+			}
+			current.WriteString(content[i : end+1])
+			i = end
+		case c == '$':
+			tag := dollarTag(content[i:])
+			if tag == "" {
+				current.WriteByte(c)
+				continue
+			}
+			end := len(content)
+			if j := strings.Index(content[i+len(tag):], tag); j >= 0 {
+				end = i + len(tag) + j + len(tag)
+			}
+			current.WriteString(content[i:end])
+			i = end - 1
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// dollarTag returns the $tag$ opening a PostgreSQL dollar-quoted string.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// splitTopLevel splits s on sep outside parentheses and quotes.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
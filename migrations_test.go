@@ -0,0 +1,152 @@
+// migrations_test.go
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMigrationKind(t *testing.T) {
+	cases := []struct {
+		path, content, want string
+	}{
+		{"db/goose/00001_init.sql", "-- +goose Up\nCREATE TABLE t (id int);\n", "goose"},
+		{"db/00001_init.sql", "-- +GOOSE UP\n", "goose"},
+		{"migrate/000001_init.up.sql", "CREATE TABLE t (id int);", "golang-migrate"},
+		{"migrate/000001_init.down.sql", "DROP TABLE t;", "golang-migrate"},
+		{"prisma/migrations/20240101_init/migration.sql", "", "prisma"},
+		{"db/migrate/20240101000000_create_posts.rb", "", "rails"},
+		{"app/db/migrate/20240101000000_create_posts.rb", "", "rails"},
+		{"migrations/V2__add_users.sql", "", "sql"},
+		{"migrations/001_init.sql", "", "sql"},
+		{"migrations/schema.sql", "", ""},
+		{"sql/001_init.sql", "", ""},
+		{"db/migrate/helper.rb", "", ""},
+		{"db/schema.rb", "", ""},
+		{"main.go", "-- +goose Up", ""},
+	}
+	for _, tc := range cases {
+		if got := migrationKind(FileResult{Path: tc.path, Content: tc.content}); got != tc.want {
+			t.Errorf("migrationKind(%s) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestLessMigration(t *testing.T) {
+	cases := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"10_c.sql", "2_b.sql", "1_a.sql"}, "1_a.sql,2_b.sql,10_c.sql"},
+		{[]string{"000010_c.up.sql", "000002_b.up.sql", "000002_b.down.sql"}, "000002_b.down.sql,000002_b.up.sql,000010_c.up.sql"},
+		{[]string{"V10__c.sql", "V9__b.sql", "v1__a.sql"}, "v1__a.sql,V9__b.sql,V10__c.sql"},
+		{[]string{"20240201_b", "20240101_a", "init"}, "20240101_a,20240201_b,init"},
+	}
+	for _, tc := range cases {
+		names := append([]string(nil), tc.names...)
+		sort.SliceStable(names, func(i, j int) bool { return lessMigration(names[i], names[j]) })
+		if got := strings.Join(names, ","); got != tc.want {
+			t.Errorf("sorted %v as %s, want %s", tc.names, got, tc.want)
+		}
+	}
+}
+
+func TestReplaySQL(t *testing.T) {
+	cases := []struct {
+		name    string
+		sql     string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "add and drop columns",
+			sql:  "CREATE TABLE users (id serial PRIMARY KEY, email text);\nALTER TABLE users ADD COLUMN age int NOT NULL;\nALTER TABLE users DROP COLUMN email;",
+			want: []string{"CREATE TABLE users (\n  id serial PRIMARY KEY,\n  age int NOT NULL\n);"},
+		},
+		{
+			name: "alter column",
+			sql:  "CREATE TABLE t (n int NOT NULL);\nALTER TABLE t ALTER COLUMN n TYPE bigint USING n::bigint;\nALTER TABLE t ALTER COLUMN n SET DEFAULT 0;\nALTER TABLE t ALTER COLUMN n DROP NOT NULL;",
+			want: []string{"  n bigint DEFAULT 0\n"},
+		},
+		{
+			name:    "rename column and table",
+			sql:     "CREATE TABLE posts (id int, title text);\nCREATE INDEX posts_title ON posts (title);\nALTER TABLE posts RENAME COLUMN title TO headline;\nALTER TABLE posts RENAME TO articles;",
+			want:    []string{"CREATE TABLE articles (\n  id int,\n  headline text\n);", "CREATE INDEX posts_title ON articles (title);"},
+			notWant: []string{"TABLE posts", "ON posts"},
+		},
+		{
+			name:    "drop renamed table with its index",
+			sql:     "CREATE TABLE posts (id int);\nCREATE INDEX posts_id ON posts (id);\nALTER TABLE posts RENAME TO articles;\nDROP TABLE articles;",
+			notWant: []string{"articles", "posts"},
+		},
+		{
+			name:    "drop constraint, index and view",
+			sql:     "CREATE TABLE t (id int, CONSTRAINT t_pk PRIMARY KEY (id));\nCREATE INDEX t_id ON t (id);\nCREATE VIEW v AS SELECT 1;\nALTER TABLE t DROP CONSTRAINT t_pk;\nDROP INDEX t_id;\nDROP VIEW v;",
+			want:    []string{"CREATE TABLE t (\n  id int\n);"},
+			notWant: []string{"t_pk", "t_id", "VIEW"},
+		},
+		{
+			name:    "data statements are left out",
+			sql:     "CREATE TABLE t (id int);\nINSERT INTO t VALUES (1);\nUPDATE t SET id = 2;",
+			notWant: []string{"INSERT", "UPDATE"},
+		},
+		{
+			name: "unfoldable alter is kept after the table",
+			sql:  "CREATE TABLE t (id int);\nALTER TABLE t ENABLE ROW LEVEL SECURITY;",
+			want: []string{");\nALTER TABLE t ENABLE ROW LEVEL SECURITY;"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schema := newSQLSchema()
+			schema.applySQL(tc.sql)
+			got := schema.String()
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("schema lacks %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tc.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("schema has %q:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConsolidateUsesSchemaFile(t *testing.T) {
+	migration := FileResult{Path: "db/migrate/20240101000000_create_posts.rb", Content: "create_table :posts do |t|\nend\n", Source: "fs"}
+	cases := []struct {
+		name  string
+		extra []string
+		want  string
+	}{
+		{name: "schema.rb", extra: []string{"db/schema.rb"}, want: "db/schema.rb"},
+		{name: "structure.sql", extra: []string{"db/structure.sql"}, want: "db/structure.sql"},
+		{name: "none", want: "db/migrate/consolidated_schema.sql"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			results := []FileResult{migration}
+			for _, path := range tc.extra {
+				results = append(results, FileResult{Path: path, Source: "fs"})
+			}
+			if got := keptPaths(consolidateMigrations(results, defaultConfig())); got != tc.want {
+				t.Errorf("kept %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReplayRails(t *testing.T) {
+	schema := newSQLSchema()
+	schema.applyRails("create_table :posts do |t|\n  t.string :title\n  t.text :body\nend\n")
+	schema.applyRails("add_column :posts, :slug, :string\nremove_column :posts, :body\nrename_column :posts, :title, :headline\n")
+	schema.applyRails("def change\n  rename_table :posts, :articles\n  drop_table :drafts\nend\ndef down\n  drop_table :articles\nend\n")
+	got := schema.String()
+	if want := "CREATE TABLE articles (\n  id primary_key,\n  headline string,\n  slug string\n);"; !strings.Contains(got, want) {
+		t.Errorf("schema lacks %q:\n%s", want, got)
+	}
+}
@@ -0,0 +1,91 @@
+// model_registry.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type ModelInfo struct {
+	ContextWindow int
+	Tokenizer     string
+}
+
+// modelRegistry lists well-known models. Unknown names are looked up on the
+// local Ollama endpoint.
+var modelRegistry = map[string]ModelInfo{
+	"gpt-4o":            {ContextWindow: 128000, Tokenizer: "o200k"},
+	"gpt-4o-mini":       {ContextWindow: 128000, Tokenizer: "o200k"},
+	"o1":                {ContextWindow: 200000, Tokenizer: "o200k"},
+	"o3-mini":           {ContextWindow: 200000, Tokenizer: "o200k"},
+	"gpt-4-turbo":       {ContextWindow: 128000, Tokenizer: "cl100k"},
+	"gpt-4":             {ContextWindow: 8192, Tokenizer: "cl100k"},
+	"gpt-3.5-turbo":     {ContextWindow: 16385, Tokenizer: "cl100k"},
+	"claude-3-5-sonnet": {ContextWindow: 200000, Tokenizer: "claude"},
+	"claude-3-opus":     {ContextWindow: 200000, Tokenizer: "claude"},
+	"claude-3-haiku":    {ContextWindow: 200000, Tokenizer: "claude"},
+	"gemini-1.5-pro":    {ContextWindow: 2000000, Tokenizer: "gemini"},
+	"gemini-1.5-flash":  {ContextWindow: 1000000, Tokenizer: "gemini"},
+	"llama3":            {ContextWindow: 8192, Tokenizer: "llama"},
+	"llama3.1":          {ContextWindow: 131072, Tokenizer: "llama"},
+	"mistral":           {ContextWindow: 32768, Tokenizer: "llama"},
+	"codellama":         {ContextWindow: 16384, Tokenizer: "llama"},
+	"qwen2.5-coder":     {ContextWindow: 32768, Tokenizer: "llama"},
+}
+
+// tokenizerBytesPerToken approximates how many bytes of source code map to
+// one token for each tokenizer family.
+var tokenizerBytesPerToken = map[string]float64{
+	"cl100k": 4.0,
+	"o200k":  4.2,
+	"claude": 3.5,
+	"gemini": 4.0,
+	"llama":  3.6,
+}
+
+// budgetShare of the context window is used for the dump; the rest is left
+// for the question and the answer.
+const budgetShare = 0.8
+
+func isValidTokenizer(name string) bool {
+	_, ok := tokenizerBytesPerToken[name]
+	return name == "" || ok
+}
+
+// resolveModel fills in the tokenizer and token budget for config.Model
+// unless they were set explicitly.
+func resolveModel(config *Config) error {
+	if config.Model == "" {
+		return nil
+	}
+
+	info, ok := modelRegistry[config.Model]
+	if !ok {
+		// Ollama tags look like "llama3:8b"; the family name carries the tokenizer.
+		family := strings.SplitN(config.Model, ":", 2)[0]
+		length, err := ollamaContextLength(ollamaEndpoint(), config.Model)
+		if err != nil {
+			return fmt.Errorf("unknown model %s and no local Ollama model with that name: %w", config.Model, err)
+		}
+		info = ModelInfo{ContextWindow: length, Tokenizer: modelRegistry[family].Tokenizer}
+	}
+
+	if config.Tokenizer == "" {
+		config.Tokenizer = info.Tokenizer
+	}
+	if config.MaxTokens == 0 {
+		config.MaxTokens = int(float64(info.ContextWindow) * budgetShare)
+	}
+	return nil
+}
+
+// estimateTokens approximates the token count for the given tokenizer,
+// falling back to the common rule of thumb of four bytes per token.
+func estimateTokens(s string, tokenizer string) int {
+	bytesPerToken, ok := tokenizerBytesPerToken[tokenizer]
+	if !ok {
+		bytesPerToken = 4.0
+	}
+	return int(math.Ceil(float64(len(s)) / bytesPerToken))
+}
@@ -0,0 +1,227 @@
+// modules.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxExportedNames caps the exported names listed per package.
+const maxExportedNames = 20
+
+// goModFile is the part of a go.mod file the module summaries use.
+type goModFile struct {
+	Module   string
+	Go       string
+	Requires []string
+	Indirect int
+}
+
+// parseGoMod reads the module, go and require directives of a go.mod file.
+func parseGoMod(data string) goModFile {
+	var mod goModFile
+	inRequire := false
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line, comment, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		indirect := strings.TrimSpace(comment) == "indirect"
+
+		switch {
+		case len(fields) == 0:
+		case inRequire && fields[0] == ")":
+			inRequire = false
+		case inRequire && len(fields) >= 2:
+			mod.addRequire(fields[0], fields[1], indirect)
+		case fields[0] == "module" && len(fields) >= 2:
+			mod.Module = strings.Trim(fields[1], `"`)
+		case fields[0] == "go" && len(fields) >= 2:
+			mod.Go = fields[1]
+		case fields[0] == "require" && len(fields) >= 2 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) >= 3:
+			mod.addRequire(fields[1], fields[2], indirect)
+		}
+	}
+	return mod
+}
+
+func (mod *goModFile) addRequire(path, version string, indirect bool) {
+	if indirect {
+		mod.Indirect++
+		return
+	}
+	mod.Requires = append(mod.Requires, path+" "+version)
+}
+
+// findModuleRoot returns the nearest directory at or above dir holding a
+// go.mod file, or "" when there is none. Lookups are cached in roots.
+func findModuleRoot(dir string, roots map[string]string) string {
+	if root, ok := roots[dir]; ok {
+		return root
+	}
+	root := ""
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = findModuleRoot(parent, roots)
+	}
+	roots[dir] = root
+	return root
+}
+
+// moduleSections returns one summary section per Go module the included Go
+// files belong to: the module path, Go version, direct requirements and the
+// importable packages with their exported names. Modules are listed in path
+// order, so the summaries mark the boundaries of a multi-module repository.
+func moduleSections(results []FileResult) []Section {
+	type pkgInfo struct {
+		name     string
+		exported []string
+	}
+	roots := make(map[string]string)
+	modules := make(map[string]map[string]*pkgInfo)
+
+	for _, result := range results {
+		if result.Source != "fs" || result.Language != "go" || strings.HasSuffix(result.Path, "_test.go") {
+			continue
+		}
+		dir := filepath.Dir(result.Path)
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		root := findModuleRoot(abs, roots)
+		if root == "" {
+			continue
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), "", result.Content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		rel, _ := filepath.Rel(root, abs)
+		if modules[root] == nil {
+			modules[root] = make(map[string]*pkgInfo)
+		}
+		pkg, ok := modules[root][filepath.ToSlash(rel)]
+		if !ok {
+			pkg = &pkgInfo{name: file.Name.Name}
+			modules[root][filepath.ToSlash(rel)] = pkg
+		}
+		pkg.exported = append(pkg.exported, exportedNames(file)...)
+	}
+
+	var rootList []string
+	for root := range modules {
+		rootList = append(rootList, root)
+	}
+	sort.Strings(rootList)
+
+	var sections []Section
+	for _, root := range rootList {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err != nil {
+			continue
+		}
+		mod := parseGoMod(string(data))
+
+		var buffer strings.Builder
+		dir := root
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, root); err == nil && !strings.HasPrefix(rel, "..") {
+				dir = rel
+			}
+		}
+		buffer.WriteString(fmt.Sprintf("Directory: %s\n", filepath.ToSlash(dir)))
+		if mod.Go != "" {
+			buffer.WriteString(fmt.Sprintf("Go: %s\n", mod.Go))
+		}
+		if len(mod.Requires) > 0 || mod.Indirect > 0 {
+			buffer.WriteString(fmt.Sprintf("Requires (%d direct, %d indirect):\n", len(mod.Requires), mod.Indirect))
+			for _, require := range mod.Requires {
+				buffer.WriteString("  " + require + "\n")
+			}
+		}
+
+		var pkgDirs []string
+		for dir := range modules[root] {
+			pkgDirs = append(pkgDirs, dir)
+		}
+		sort.Strings(pkgDirs)
+		buffer.WriteString("Packages:\n")
+		for _, dir := range pkgDirs {
+			pkg := modules[root][dir]
+			importPath := path.Join(mod.Module, dir)
+			switch {
+			case pkg.name == "main":
+				buffer.WriteString(fmt.Sprintf("  %s (command)\n", importPath))
+			case isInternalPath(dir):
+				buffer.WriteString(fmt.Sprintf("  %s (internal)\n", importPath))
+			default:
+				sort.Strings(pkg.exported)
+				exported := pkg.exported
+				if len(exported) > maxExportedNames {
+					exported = append(exported[:maxExportedNames:maxExportedNames], fmt.Sprintf("and %d more", len(pkg.exported)-maxExportedNames))
+				}
+				buffer.WriteString(fmt.Sprintf("  %s: %s\n", importPath, strings.Join(exported, ", ")))
+			}
+		}
+		sections = append(sections, Section{Title: "Module " + mod.Module, Content: buffer.String()})
+	}
+	return sections
+}
+
+func isInternalPath(dir string) bool {
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedNames lists the exported top-level identifiers of file; methods
+// are named Type.Method.
+func exportedNames(file *ast.File) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				if recv := receiverName(d.Recv.List[0].Type); ast.IsExported(recv) {
+					names = append(names, recv+"."+d.Name.Name)
+				}
+				continue
+			}
+			names = append(names, d.Name.Name)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						names = append(names, s.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.IsExported() {
+							names = append(names, name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,75 @@
+// netfs.go
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// networkMinWorkers is the default lower bound of the worker count with
+// --network-fs: many reads in flight hide the round trip of each one.
+const networkMinWorkers = 8
+
+// readNetwork is readStable for network filesystems, where every metadata
+// call is a round trip: the file is opened and stat'ed once, and a read
+// that does not match the size seen at open is retried once before the
+// file counts as changed. Transient errors such as stale NFS handles are
+// retried under the "fs" retry policy.
+func readNetwork(fsys fs.FS, name string) ([]byte, fs.FileInfo, error) {
+	var content []byte
+	var info fs.FileInfo
+	err := retry("fs", func() error {
+		var err error
+		for attempt := 0; attempt < 2; attempt++ {
+			content, info, err = readOnce(fsys, name)
+			if err != errFileChanged {
+				break
+			}
+		}
+		if isTransientFSError(err) {
+			return retryable(err)
+		}
+		return err
+	})
+	return content, info, err
+}
+
+func readOnce(fsys fs.FS, name string) ([]byte, fs.FileInfo, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	content := make([]byte, 0, info.Size()+1)
+	for {
+		n, err := file.Read(content[len(content):cap(content)])
+		content = content[:len(content)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(content) == cap(content) {
+			// Longer than at open: it grew while being read.
+			return nil, nil, errFileChanged
+		}
+	}
+	if int64(len(content)) != info.Size() {
+		return nil, nil, errFileChanged
+	}
+	return content, info, nil
+}
+
+// isTransientFSError reports errors a network filesystem may recover
+// from: stale handles after a server failover, I/O errors and timeouts
+// (see isTransientErrno).
+func isTransientFSError(err error) bool {
+	return isTransientErrno(err) || errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,17 @@
+// netfs_errno.go
+//go:build !plan9
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isTransientErrno reports the system errors a network filesystem may
+// recover from: stale handles after a server failover, I/O errors and
+// timeouts.
+func isTransientErrno(err error) bool {
+	return errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.ETIMEDOUT) || errors.Is(err, syscall.EINTR)
+}
@@ -0,0 +1,10 @@
+// netfs_plan9.go
+//go:build plan9
+
+package main
+
+// isTransientErrno is always false here; Plan 9 reports errors as strings,
+// not errno values.
+func isTransientErrno(err error) bool {
+	return false
+}
@@ -0,0 +1,77 @@
+// network.go
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"os/exec"
+)
+
+// networkTLS is the TLS configuration of every outgoing connection. It
+// trusts the system roots plus CODEXGIGANTUS_CA_BUNDLE (a PEM file, e.g.
+// the CA of a TLS-intercepting proxy); CODEXGIGANTUS_INSECURE_SKIP_VERIFY=1
+// turns verification off altogether.
+var networkTLS = loadNetworkTLS()
+
+// networkTransport is shared by all HTTP clients. Proxies come from
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+var networkTransport = newNetworkTransport()
+
+func loadNetworkTLS() *tls.Config {
+	config := &tls.Config{}
+
+	if bundle := os.Getenv("CODEXGIGANTUS_CA_BUNDLE"); bundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(bundle)
+		if err != nil {
+			defaultLogger.Printf("WARNING: reading CODEXGIGANTUS_CA_BUNDLE: %v", err)
+		} else if !pool.AppendCertsFromPEM(pem) {
+			defaultLogger.Printf("WARNING: CODEXGIGANTUS_CA_BUNDLE %s holds no PEM certificates", bundle)
+		}
+		config.RootCAs = pool
+	}
+
+	if skipTLSVerify() {
+		defaultLogger.Printf("WARNING: TLS certificate verification is disabled")
+		config.InsecureSkipVerify = true
+	}
+	return config
+}
+
+func skipTLSVerify() bool {
+	value := os.Getenv("CODEXGIGANTUS_INSECURE_SKIP_VERIFY")
+	return value == "1" || value == "true"
+}
+
+func newNetworkTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = networkTLS
+	return transport
+}
+
+// tlsConfigFor returns the shared TLS settings for a connection to host.
+func tlsConfigFor(host string) *tls.Config {
+	config := networkTLS.Clone()
+	config.ServerName = host
+	return config
+}
+
+// gitCommand runs git with the same CA and verification settings; git reads
+// the proxy variables on its own.
+func gitCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Env = os.Environ()
+	if bundle := os.Getenv("CODEXGIGANTUS_CA_BUNDLE"); bundle != "" {
+		cmd.Env = append(cmd.Env, "GIT_SSL_CAINFO="+bundle)
+	}
+	if skipTLSVerify() {
+		cmd.Env = append(cmd.Env, "GIT_SSL_NO_VERIFY=true")
+	}
+	return cmd
+}
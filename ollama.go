@@ -0,0 +1,162 @@
+// ollama.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultOllamaEndpoint = "http://localhost:11434"
+
+type OllamaModel struct {
+	Name          string
+	Size          int64
+	ContextLength int
+}
+
+var ollamaClient = &http.Client{Timeout: 10 * time.Second, Transport: networkTransport}
+
+func runModels(args []string) error {
+	fs := flag.NewFlagSet("models", flag.ExitOnError)
+	endpointFlag := fs.String("endpoint", ollamaEndpoint(), "Comma-separated list of Ollama endpoints (default: $OLLAMA_HOST or "+defaultOllamaEndpoint+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, endpoint := range parseCommaSeparated(*endpointFlag) {
+		fmt.Printf("Endpoint: %s\n", endpoint)
+		if err := checkOllama(endpoint); err != nil {
+			fmt.Printf("  unavailable: %v\n\n", err)
+			failed++
+			continue
+		}
+
+		models, err := listOllamaModels(endpoint)
+		if err != nil {
+			fmt.Printf("  error listing models: %v\n\n", err)
+			failed++
+			continue
+		}
+		if len(models) == 0 {
+			fmt.Println("  no models installed")
+		}
+		for _, model := range models {
+			context := "unknown"
+			if model.ContextLength > 0 {
+				context = fmt.Sprintf("%d tokens", model.ContextLength)
+			}
+			fmt.Printf("  %-40s context: %-14s size: %s\n", model.Name, context, formatSize(model.Size))
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d endpoint(s) failed the health check", failed)
+	}
+	return nil
+}
+
+func ollamaEndpoint() string {
+	host := os.Getenv("OLLAMA_HOST")
+	if host == "" {
+		return defaultOllamaEndpoint
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return host
+}
+
+func checkOllama(endpoint string) error {
+	req, err := http.NewRequest("GET", strings.TrimRight(endpoint, "/")+"/api/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doHTTP(ollamaClient, req, "ollama")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func listOllamaModels(endpoint string) ([]OllamaModel, error) {
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"models"`
+	}
+	if err := ollamaRequest(endpoint, "GET", "/api/tags", nil, &tags); err != nil {
+		return nil, err
+	}
+
+	var models []OllamaModel
+	for _, m := range tags.Models {
+		model := OllamaModel{Name: m.Name, Size: m.Size}
+		if length, err := ollamaContextLength(endpoint, m.Name); err == nil {
+			model.ContextLength = length
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// ollamaContextLength reads the model's context window from /api/show,
+// where it is reported as "<architecture>.context_length".
+func ollamaContextLength(endpoint, model string) (int, error) {
+	var show struct {
+		ModelInfo map[string]interface{} `json:"model_info"`
+	}
+	if err := ollamaRequest(endpoint, "POST", "/api/show", map[string]string{"model": model}, &show); err != nil {
+		return 0, err
+	}
+
+	for key, value := range show.ModelInfo {
+		if strings.HasSuffix(key, ".context_length") {
+			if n, ok := value.(float64); ok {
+				return int(n), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("model %s does not report a context length", model)
+}
+
+func ollamaRequest(endpoint, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(endpoint, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := doHTTP(ollamaClient, req, "ollama")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status %s", method, path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,52 @@
+// osfs.go
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// osFS is the fs.FS the processor walks for a directory on disk. Unlike
+// os.DirFS it goes through longPath, so long paths and reserved names keep
+// working on Windows.
+type osFS string
+
+func (root osFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return longPath(filepath.Join(string(root), filepath.FromSlash(name))), nil
+}
+
+func (root osFS) Open(name string) (fs.File, error) {
+	path, err := root.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (root osFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := root.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+func (root osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := root.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(path)
+}
+
+func (root osFS) ReadFile(name string) ([]byte, error) {
+	path, err := root.path("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
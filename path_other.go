@@ -0,0 +1,14 @@
+// path_other.go
+//go:build !windows
+
+package main
+
+// longPath is only needed on Windows.
+func longPath(path string) string {
+	return path
+}
+
+// safeFileName is only needed on Windows.
+func safeFileName(name string) string {
+	return name
+}
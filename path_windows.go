@@ -0,0 +1,62 @@
+// path_windows.go
+//go:build windows
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath leaves room below MAX_PATH (260) for the names Windows appends
+// when creating directories.
+const maxPath = 248
+
+// longPath returns path in the \\?\ form when it is too long for the Win32
+// API or names a reserved device, so such files are still opened as files.
+func longPath(path string) string {
+	if strings.HasPrefix(path, `\\?\`) || (len(path) < maxPath && !hasReservedName(path)) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// safeFileName renames generated file names that Windows would open as a
+// device (con.txt, nul.txt, ...).
+func safeFileName(name string) string {
+	if isReservedName(name) {
+		return "_" + name
+	}
+	return name
+}
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isReservedName reports whether Windows treats name as a device, which
+// also holds with an extension ("nul.txt") or trailing spaces.
+func isReservedName(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+func hasReservedName(path string) bool {
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if isReservedName(part) {
+			return true
+		}
+	}
+	return false
+}
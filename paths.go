@@ -0,0 +1,100 @@
+// paths.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// canonicalDirs cleans the directories to walk and puts those below the
+// working directory in relative form, so ./src/, src and $PWD/src all
+// report the same paths. Directories given twice are walked once.
+func canonicalDirs(dirs []string) []string {
+	wd, _ := os.Getwd()
+	seen := make(map[string]bool)
+	var canonical []string
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if filepath.IsAbs(dir) && wd != "" {
+			if rel, err := filepath.Rel(wd, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				dir = rel
+			}
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			canonical = append(canonical, dir)
+		}
+	}
+	return canonical
+}
+
+// uniqueFiles drops the results of a walk of dir that were already
+// included under another path: through a symlink, or a directory that is
+// given twice in another form or lies inside another one. seen maps the
+// resolved paths to the first path they were included as. With
+// --network-fs only dir itself is resolved, not every file.
+func uniqueFiles(results []FileResult, dir string, seen map[string]string, config *Config) []FileResult {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return results
+	}
+	if realDir, err = filepath.Abs(realDir); err != nil {
+		return results
+	}
+
+	var unique []FileResult
+	for _, result := range results {
+		if result.Source != "fs" {
+			unique = append(unique, result)
+			continue
+		}
+		// located is where the entry itself lives, real the file it is.
+		located := realDir
+		if rel, err := filepath.Rel(dir, filepath.FromSlash(result.Path)); err == nil {
+			located = filepath.Join(realDir, rel)
+		}
+		real := located
+		if !config.NetworkFS {
+			if info, err := os.Lstat(located); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				if target, err := filepath.EvalSymlinks(located); err == nil {
+					real, _ = filepath.Abs(target)
+				}
+			}
+		}
+		// A symlink met again through an overlapping directory was
+		// already reported the first time.
+		if _, ok := seen[located]; ok && located != real {
+			config.Debugf("Already seen: %s", result.Path)
+			continue
+		}
+		if first, ok := seen[real]; ok {
+			if first == result.Path {
+				config.Debugf("Already included: %s", result.Path)
+			} else {
+				config.Warn("duplicate", result.Path, "same file as %s, skipped", first)
+			}
+			seen[located] = first
+			continue
+		}
+		seen[real] = result.Path
+		seen[located] = result.Path
+		unique = append(unique, result)
+	}
+	return unique
+}
+
+// reportCaseCollisions warns about paths that differ only in case: a
+// checkout or restore on a case-insensitive filesystem (macOS, Windows)
+// keeps only one of them.
+func reportCaseCollisions(results []FileResult, config *Config) {
+	first := make(map[string]string)
+	for _, result := range results {
+		folded := strings.ToLower(result.Path)
+		if other, ok := first[folded]; ok && other != result.Path {
+			config.Warn("case", result.Path, "differs from %s only in case; they collide on case-insensitive filesystems", other)
+			continue
+		}
+		first[folded] = result.Path
+	}
+}
@@ -0,0 +1,136 @@
+// paths_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalDirs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	cases := []struct {
+		name string
+		dirs []string
+		want []string
+	}{
+		{name: "relative forms", dirs: []string{"./testdata/", "testdata", filepath.Join(wd, "testdata")}, want: []string{"testdata"}},
+		{name: "working directory", dirs: []string{wd, "."}, want: []string{"."}},
+		{name: "absolute outside", dirs: []string{outside, outside + string(filepath.Separator)}, want: []string{outside}},
+		{name: "parent", dirs: []string{filepath.Dir(wd)}, want: []string{filepath.Dir(wd)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := canonicalDirs(tc.dirs)
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("canonicalDirs(%v) = %v, want %v", tc.dirs, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessFilesSkipsDuplicates(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.go", "src/lib.go"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("lib.go", filepath.Join(root, "src", "shortcut.go")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	if err := os.Symlink("src", filepath.Join(root, "linked")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		dirs       []string
+		kept       string
+		duplicates string
+	}{
+		{name: "file symlink", dirs: []string{root}, kept: "main.go,src/lib.go", duplicates: "src/shortcut.go"},
+		{name: "dir given twice", dirs: []string{root, root + "/"}, kept: "main.go,src/lib.go", duplicates: "src/shortcut.go"},
+		{name: "dir inside another", dirs: []string{root, filepath.Join(root, "src")}, kept: "main.go,src/lib.go", duplicates: "src/shortcut.go"},
+		{name: "symlinked dir", dirs: []string{filepath.Join(root, "src"), filepath.Join(root, "linked")}, kept: "src/lib.go", duplicates: "src/shortcut.go,linked/lib.go"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := defaultConfig()
+			config.Dirs = tc.dirs
+			config.Warnings = &Warnings{}
+			results, err := ProcessFiles(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range results {
+				results[i].Path = strings.TrimPrefix(results[i].Path, filepath.ToSlash(root)+"/")
+			}
+			if got := keptPaths(results); got != tc.kept {
+				t.Errorf("kept %s, want %s", got, tc.kept)
+			}
+			var duplicates []string
+			for _, warning := range config.Warnings.List() {
+				if warning.Kind == "duplicate" {
+					duplicates = append(duplicates, strings.TrimPrefix(warning.Path, filepath.ToSlash(root)+"/"))
+				}
+			}
+			if got := strings.Join(duplicates, ","); got != tc.duplicates {
+				t.Errorf("duplicate warnings for %s, want %s", got, tc.duplicates)
+			}
+		})
+	}
+}
+
+func TestReportCaseCollisions(t *testing.T) {
+	config := defaultConfig()
+	config.Warnings = &Warnings{}
+	var results []FileResult
+	for _, path := range []string{"README.md", "docs/Guide.md", "readme.md", "docs/guide.md", "docs/GUIDE.md", "src/main.go"} {
+		results = append(results, FileResult{Path: path})
+	}
+	reportCaseCollisions(results, config)
+
+	var got []string
+	for _, warning := range config.Warnings.List() {
+		if warning.Kind == "case" {
+			got = append(got, warning.Path+" ~ "+strings.TrimPrefix(strings.Split(warning.Message, " only")[0], "differs from "))
+		}
+	}
+	want := "readme.md ~ README.md,docs/guide.md ~ docs/Guide.md,docs/GUIDE.md ~ docs/Guide.md"
+	if strings.Join(got, ",") != want {
+		t.Errorf("case warnings %v, want %s", got, want)
+	}
+}
+
+func TestProcessFilesKeepsConfigDirs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig()
+	dir := filepath.Join(wd, "testdata", "corpus") + string(filepath.Separator)
+	config.Dirs = []string{dir}
+	config.Priorities = map[string]int{"docs": 10}
+	results, err := ProcessFiles(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Dirs) != 1 || config.Dirs[0] != dir {
+		t.Errorf("config.Dirs became %v, want %s", config.Dirs, dir)
+	}
+	if len(results) == 0 {
+		t.Fatal("no results")
+	}
+	if results[0].Path != "testdata/corpus/docs/guide.md" {
+		t.Errorf("priorities did not match the canonical paths: %s comes first", results[0].Path)
+	}
+}
@@ -0,0 +1,233 @@
+// pipeline.go
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// adaptWindow is the number of files between two worker pool adjustments.
+const adaptWindow = 16
+
+// filePipeline reads the files a walk finds and runs NewFileResult (content
+// transforms and token counting) on a pool of workers, so slow reads and
+// CPU-heavy transforms overlap. Results come back in walk order.
+//
+// With config.Workers unset the pool size adapts: it starts at one worker
+// per CPU, grows while reads take longer than processing (network
+// filesystems, cold caches) and shrinks again when read latency climbs
+// after growing, staying within config.MinWorkers and config.MaxWorkers.
+type filePipeline struct {
+	fsys    fs.FS
+	config  *Config
+	pool    *workerPool
+	pending []*pendingResult
+	// emitted counts the pending results already handed to --tail.
+	emitted int
+
+	adaptive bool
+	statsMu  sync.Mutex
+	files    int
+	read     time.Duration
+	process  time.Duration
+	// lastRead and lastLimit describe the previous window.
+	lastRead  time.Duration
+	lastLimit int
+}
+
+type pendingResult struct {
+	done   chan struct{}
+	result FileResult
+	skip   bool
+	err    error
+}
+
+func newFilePipeline(fsys fs.FS, config *Config) *filePipeline {
+	p := &filePipeline{fsys: fsys, config: config}
+	if config.Workers > 0 {
+		p.pool = newWorkerPool(config.Workers)
+		return p
+	}
+	p.adaptive = true
+	p.pool = newWorkerPool(clampWorkers(runtime.NumCPU(), config))
+	return p
+}
+
+// clampWorkers bounds n by config.MinWorkers (default 1, or
+// networkMinWorkers with --network-fs) and config.MaxWorkers (default four
+// per CPU).
+func clampWorkers(n int, config *Config) int {
+	low, high := config.MinWorkers, config.MaxWorkers
+	if low <= 0 {
+		low = 1
+		if config.NetworkFS {
+			low = networkMinWorkers
+		}
+	}
+	if high <= 0 {
+		high = 4 * runtime.NumCPU()
+	}
+	if high < low {
+		high = low
+	}
+	return max(low, min(n, high))
+}
+
+// submit queues a file found by the walk; label and source are what the
+// result reports. It blocks while every worker is busy, which bounds the
+// file contents held in memory.
+func (p *filePipeline) submit(name, label, source string) {
+	pending := &pendingResult{done: make(chan struct{})}
+	p.pending = append(p.pending, pending)
+	p.pool.acquire()
+	go func() {
+		defer p.pool.release()
+		defer close(pending.done)
+
+		read := readStable
+		if p.config.NetworkFS {
+			read = readNetwork
+		}
+		start := time.Now()
+		content, info, err := read(p.fsys, name)
+		readTime := time.Since(start)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			p.config.Warn("vanished", label, "disappeared during processing, skipped")
+			pending.skip = true
+		case err == errFileChanged:
+			p.config.Warn("changed", label, "kept changing while being read, skipped")
+			pending.skip = true
+		case err != nil:
+			pending.err = err
+		case p.config.NetworkFS && !matchesPlatformContent(name, content, p.config):
+			p.config.Debugf("Ignoring file built for another platform: %s", label)
+			pending.skip = true
+		default:
+			pending.result = newFileResult(label, name, source, content, info.ModTime(), p.config)
+			p.observe(readTime, time.Since(start)-readTime)
+		}
+	}()
+	p.flush(false)
+}
+
+// observe records the timings of one file and resizes the pool at the end
+// of every window.
+func (p *filePipeline) observe(read, process time.Duration) {
+	if !p.adaptive {
+		return
+	}
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	p.files++
+	p.read += read
+	p.process += process
+	if p.files < adaptWindow {
+		return
+	}
+
+	avgRead, avgProcess := p.read/time.Duration(p.files), p.process/time.Duration(p.files)
+	limit := p.pool.size()
+	next := limit
+	switch {
+	case p.lastRead > 0 && limit > p.lastLimit && avgRead > 2*p.lastRead:
+		// Latency rose with the extra workers: the storage is saturated.
+		next = limit / 2
+	case avgRead > avgProcess:
+		next = limit + max(1, limit/2)
+	default:
+		next = runtime.NumCPU()
+	}
+	next = clampWorkers(next, p.config)
+	if next != limit {
+		p.config.Debugf("Workers: %d -> %d (read %s, process %s per file)", limit, next, avgRead, avgProcess)
+		p.pool.resize(next)
+	}
+	p.lastRead, p.lastLimit = avgRead, limit
+	p.files, p.read, p.process = 0, 0, 0
+}
+
+// flush hands finished results to --tail in walk order, waiting for
+// unfinished ones only when wait is set.
+func (p *filePipeline) flush(wait bool) {
+	for p.emitted < len(p.pending) {
+		pending := p.pending[p.emitted]
+		if wait {
+			<-pending.done
+		} else {
+			select {
+			case <-pending.done:
+			default:
+				return
+			}
+		}
+		if !pending.skip && pending.err == nil {
+			p.config.tail.add(pending.result, p.config)
+		}
+		p.emitted++
+	}
+}
+
+// wait returns the results in walk order once the workers are done, or the
+// first read error.
+func (p *filePipeline) wait() ([]FileResult, error) {
+	p.flush(true)
+	results := make([]FileResult, 0, len(p.pending))
+	for _, pending := range p.pending {
+		if pending.err != nil {
+			return nil, pending.err
+		}
+		if !pending.skip {
+			results = append(results, pending.result)
+		}
+	}
+	return results, nil
+}
+
+// workerPool is a semaphore whose size can change while it is in use.
+type workerPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+}
+
+func newWorkerPool(limit int) *workerPool {
+	pool := &workerPool{limit: limit}
+	pool.cond = sync.NewCond(&pool.mu)
+	return pool
+}
+
+func (pool *workerPool) acquire() {
+	pool.mu.Lock()
+	for pool.active >= pool.limit {
+		pool.cond.Wait()
+	}
+	pool.active++
+	pool.mu.Unlock()
+}
+
+func (pool *workerPool) release() {
+	pool.mu.Lock()
+	pool.active--
+	pool.mu.Unlock()
+	pool.cond.Broadcast()
+}
+
+func (pool *workerPool) size() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.limit
+}
+
+// resize changes the number of workers; running ones finish first when it
+// shrinks.
+func (pool *workerPool) resize(limit int) {
+	pool.mu.Lock()
+	pool.limit = limit
+	pool.mu.Unlock()
+	pool.cond.Broadcast()
+}
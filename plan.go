@@ -0,0 +1,159 @@
+// plan.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runPlan implements the two-pass workflow: pass 1 shows a numbered codemap,
+// pass 2 dumps only the files picked from it.
+func runPlan(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	selectFlag := fs.String("select", "", "File with the selection (numbers, ranges or paths); '-' reads stdin. Prompts interactively when empty")
+	mapOnlyFlag := fs.Bool("map-only", false, "Only print the numbered codemap (pass 1) and exit")
+
+	config, err := ParseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if config.Format == "codemap" {
+		config.Format = "text"
+	}
+	if err := ValidateConfig(config); err != nil {
+		return err
+	}
+	if err := resolveModel(config); err != nil {
+		return err
+	}
+
+	results, err := ProcessFiles(config)
+	if err != nil {
+		return err
+	}
+
+	codemap := formatNumberedCodemap(results)
+	if *mapOnlyFlag {
+		fmt.Print(codemap)
+		return nil
+	}
+
+	var selection string
+	switch *selectFlag {
+	case "":
+		fmt.Print(codemap)
+		fmt.Print("\nSelect files (numbers, ranges like 3-7, or paths; empty selects all): ")
+		selection, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+	case "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		selection = string(data)
+	default:
+		data, err := os.ReadFile(*selectFlag)
+		if err != nil {
+			return err
+		}
+		selection = string(data)
+	}
+
+	selected, err := selectResults(results, selection)
+	if err != nil {
+		return err
+	}
+	config.Debugf("Selected %d of %d files", len(selected), len(results))
+
+	sections, err := BuildSections(selected, config)
+	if err != nil {
+		return err
+	}
+
+	return EmitChunks(selected, sections, config)
+}
+
+func formatNumberedCodemap(results []FileResult) string {
+	var buffer bytes.Buffer
+
+	for i, result := range results {
+		buffer.WriteString(fmt.Sprintf("[%d] %s: %s\n", i+1, filepath.ToSlash(result.Path), summarizeFile(result.Path, result.Content)))
+	}
+
+	return buffer.String()
+}
+
+// selectResults resolves a free-form selection against the codemap. Entries
+// may be separated by commas, spaces or newlines, and may carry list bullets
+// or backticks as produced by an LLM.
+func selectResults(results []FileResult, selection string) ([]FileResult, error) {
+	fields := strings.FieldsFunc(selection, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t' || r == '\r'
+	})
+	if len(fields) == 0 {
+		return results, nil
+	}
+
+	picked := make([]bool, len(results))
+	for _, field := range fields {
+		field = strings.Trim(field, "-*`[]:")
+		if field == "" {
+			continue
+		}
+
+		if start, end, ok := parseRange(field); ok {
+			if start < 1 || end > len(results) || start > end {
+				return nil, fmt.Errorf("selection %q is out of range 1-%d", field, len(results))
+			}
+			for i := start; i <= end; i++ {
+				picked[i-1] = true
+			}
+			continue
+		}
+
+		found := false
+		for i, result := range results {
+			path := filepath.ToSlash(result.Path)
+			if path == field || strings.HasPrefix(path, strings.TrimSuffix(field, "/")+"/") {
+				picked[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("selection %q does not match any file", field)
+		}
+	}
+
+	var selected []FileResult
+	for i, result := range results {
+		if picked[i] {
+			selected = append(selected, result)
+		}
+	}
+	return selected, nil
+}
+
+func parseRange(s string) (int, int, bool) {
+	from, to, isRange := strings.Cut(s, "-")
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, start, true
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
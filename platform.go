@@ -0,0 +1,56 @@
+// platform.go
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// matchesPlatform reports whether a Go or assembly file of fsys is built
+// for the --goos/--goarch target, judging by its _os_arch name suffix and
+// its //go:build constraints. Other files always match. The unset one of
+// the two defaults to the host, and cgo is off when cross-compiling, as it
+// is for go build.
+func matchesPlatform(fsys fs.FS, name string, config *Config) bool {
+	return matchPlatform(name, config, func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	})
+}
+
+// matchesPlatformContent is matchesPlatform for a file that has already
+// been read, saving the extra open on network filesystems.
+func matchesPlatformContent(name string, content []byte, config *Config) bool {
+	return matchPlatform(name, config, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+}
+
+func matchPlatform(name string, config *Config, open func(string) (io.ReadCloser, error)) bool {
+	if config.GOOS == "" && config.GOARCH == "" {
+		return true
+	}
+	if !strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, ".s") {
+		return true
+	}
+
+	ctxt := build.Default
+	if config.GOOS != "" {
+		ctxt.GOOS = config.GOOS
+	}
+	if config.GOARCH != "" {
+		ctxt.GOARCH = config.GOARCH
+	}
+	if ctxt.GOOS != runtime.GOOS || ctxt.GOARCH != runtime.GOARCH {
+		ctxt.CgoEnabled = false
+	}
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = open
+
+	match, err := ctxt.MatchFile(path.Dir(name), path.Base(name))
+	return err != nil || match
+}
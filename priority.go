@@ -0,0 +1,100 @@
+// priority.go
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prioritizeResults orders the results by the weight of the most specific
+// config.Priorities pattern matching them, highest first. Files no pattern
+// matches weigh 0; equal weights keep the walk order.
+func prioritizeResults(results []FileResult, config *Config) []FileResult {
+	weights := make([]int, len(results))
+	for i, result := range results {
+		weights[i] = priorityWeight(result, config)
+	}
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return weights[order[i]] > weights[order[j]]
+	})
+
+	sorted := make([]FileResult, len(results))
+	for i, index := range order {
+		sorted[i] = results[index]
+	}
+	return sorted
+}
+
+// priorityWeight returns the weight of the longest pattern matching result.
+// A pattern matches the result's source ("dep", "std", a clone label), a
+// directory below the walked one and everything in it, or, when it holds
+// glob characters, the path relative to the walked directory or the file
+// name.
+func priorityWeight(result FileResult, config *Config) int {
+	rel := filepath.ToSlash(filepath.Join(append(parentDirs(result.Path, config.Dirs), filepath.Base(result.Path))...))
+	weight, matched := 0, ""
+	for pattern, w := range config.Priorities {
+		if !priorityMatches(pattern, result.Source, rel) {
+			continue
+		}
+		if len(pattern) > len(matched) || (len(pattern) == len(matched) && w > weight) {
+			weight, matched = w, pattern
+		}
+	}
+	return weight
+}
+
+func priorityMatches(pattern, source, rel string) bool {
+	if pattern == source {
+		return true
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		ok, _ := filepath.Match(pattern, filepath.Base(rel))
+		return ok
+	}
+	dir := strings.Trim(filepath.ToSlash(pattern), "/")
+	return rel == dir || strings.HasPrefix(rel, dir+"/")
+}
+
+// parsePriorities reads the -priority flag: pattern=weight pairs separated
+// by commas.
+func parsePriorities(s string) (map[string]int, error) {
+	priorities := make(map[string]int)
+	for _, pair := range parseCommaSeparated(s) {
+		pattern, value, ok := strings.Cut(pair, "=")
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || strings.TrimSpace(pattern) == "" || err != nil {
+			return nil, fmt.Errorf("invalid priority %q, want pattern=weight", pair)
+		}
+		priorities[strings.TrimSpace(pattern)] = weight
+	}
+	return priorities, nil
+}
+
+// formatPriorities is the inverse of parsePriorities, sorted by weight.
+func formatPriorities(priorities map[string]int) string {
+	var patterns []string
+	for pattern := range priorities {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if priorities[patterns[i]] != priorities[patterns[j]] {
+			return priorities[patterns[i]] > priorities[patterns[j]]
+		}
+		return patterns[i] < patterns[j]
+	})
+	for i, pattern := range patterns {
+		patterns[i] = fmt.Sprintf("%s=%d", pattern, priorities[pattern])
+	}
+	return strings.Join(patterns, ",")
+}
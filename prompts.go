@@ -0,0 +1,57 @@
+// prompts.go
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+type promptTemplate struct {
+	Intro string
+	Outro string
+}
+
+// promptTemplates wrap the generated context with task-specific
+// instructions. {details} is replaced with the -prompt-details text.
+var promptTemplates = map[string]promptTemplate{
+	"bug-report": {
+		Intro: "You are debugging the codebase below. A user reported the following bug:\n\n{details}\n\nThe relevant source files follow.",
+		Outro: "Identify the most likely root cause of the reported bug, point to the exact files and lines involved, and propose a minimal fix as a unified diff.",
+	},
+	"refactor-request": {
+		Intro: "You are refactoring the codebase below. The requested refactoring is:\n\n{details}\n\nThe relevant source files follow.",
+		Outro: "Propose the refactoring as unified diffs. Preserve existing behaviour, keep the public API stable unless asked otherwise, and explain any risky change.",
+	},
+	"code-review": {
+		Intro: "You are an experienced reviewer. Review the code below.\n\n{details}",
+		Outro: "List correctness bugs, security issues, and maintainability problems in order of severity, referencing file paths and lines. Suggest concrete fixes.",
+	},
+	"test-generation": {
+		Intro: "You are writing tests for the codebase below.\n\n{details}",
+		Outro: "Write unit tests for the code above following the project's existing conventions. Cover edge cases and error paths, and state any assumptions.",
+	},
+}
+
+func promptTemplateNames() []string {
+	var names []string
+	for name := range promptTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isValidPromptTemplate(name string) bool {
+	_, ok := promptTemplates[name]
+	return name == "" || ok
+}
+
+func applyPromptTemplate(name, details, body string, vars map[string]func() string) string {
+	tmpl, ok := promptTemplates[name]
+	if !ok {
+		return body
+	}
+
+	intro := strings.TrimSpace(strings.ReplaceAll(tmpl.Intro, "{details}", expandTemplate(details, vars)))
+	return expandTemplate(intro, vars) + "\n\n" + body + "\n" + expandTemplate(tmpl.Outro, vars) + "\n"
+}
@@ -0,0 +1,177 @@
+// prune.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPruneEntries caps the directories and files offered by --prune.
+const maxPruneEntries = 20
+
+// pruneEntry is a directory or file that --prune offers to exclude.
+type pruneEntry struct {
+	rule ignoreSuggestion
+	// label is the path shown, relative to the walked directory.
+	label    string
+	excluded bool
+}
+
+// pruneCandidates lists the largest directories (with more than one file)
+// and files of the walked directories, most tokens first.
+func pruneCandidates(results []FileResult, dirs []string) []*pruneEntry {
+	byDir := make(map[string]*ignoreSuggestion)
+	var entries []*pruneEntry
+	for _, result := range results {
+		if result.Source != "fs" {
+			continue
+		}
+		parents := parentDirs(result.Path, dirs)
+		entries = append(entries, &pruneEntry{
+			rule:  ignoreSuggestion{Kind: "file", Value: "/" + walkedPath(result.Path, dirs), Files: 1, Tokens: result.TokenCount},
+			label: filepath.Join(append(parents, filepath.Base(result.Path))...),
+		})
+
+		for i := range parents {
+			dir := filepath.Join(parents[:i+1]...)
+			if byDir[dir] == nil {
+				byDir[dir] = &ignoreSuggestion{Kind: "dir", Value: "/" + filepath.ToSlash(dir)}
+			}
+			byDir[dir].Files++
+			byDir[dir].Tokens += result.TokenCount
+		}
+	}
+	for _, dir := range byDir {
+		if dir.Files > 1 {
+			entries = append(entries, &pruneEntry{rule: *dir, label: filepath.FromSlash(strings.TrimPrefix(dir.Value, "/")) + string(filepath.Separator)})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].rule.Tokens != entries[j].rule.Tokens {
+			return entries[i].rule.Tokens > entries[j].rule.Tokens
+		}
+		return entries[i].rule.flag() < entries[j].rule.flag()
+	})
+	if len(entries) > maxPruneEntries {
+		entries = entries[:maxPruneEntries]
+	}
+	return entries
+}
+
+// applyPrune drops the results matched by an excluded entry. Entries are
+// anchored at the walked directory (/docs, /docs/README.md), as the walk
+// reads them from the saved profile, so they drop exactly what they list.
+func applyPrune(results []FileResult, entries []*pruneEntry, dirs []string) []FileResult {
+	var kept []FileResult
+	for _, result := range results {
+		dropped := false
+		for _, entry := range entries {
+			if entry.excluded && result.Source == "fs" && ruleMatches(entry.rule.Kind, entry.rule.Value, result.Path, dirs) {
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// pruneResults lets the user exclude the largest directories and files of
+// an over-budget run until it fits, then offers to save the resulting
+// filters as a profile. Prompts go to stderr, as stdout may carry the
+// output. It does nothing when stdin is not a terminal.
+func pruneResults(results []FileResult, config *Config) ([]FileResult, error) {
+	total := totalTokens(results)
+	if config.MaxTokens <= 0 || total <= config.MaxTokens {
+		return results, nil
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		config.Warn("prune", "", "stdin is not a terminal, not pruning")
+		return results, nil
+	}
+
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stderr}
+	entries := pruneCandidates(results, config.Dirs)
+	kept := results
+	for {
+		total = totalTokens(kept)
+		fmt.Fprintf(p.out, "\n%d of %d tokens budgeted, in %d files.\n", total, config.MaxTokens, len(kept))
+		for i, entry := range entries {
+			mark := " "
+			if entry.excluded {
+				mark = "x"
+			}
+			fmt.Fprintf(p.out, "  %2d. [%s] %-4s %-50s %8d tokens in %d file(s)\n", i+1, mark, entry.rule.Kind, entry.label, entry.rule.Tokens, entry.rule.Files)
+		}
+		status := "Over budget"
+		if total <= config.MaxTokens {
+			status = "Fits"
+		}
+		answer, err := p.ask(status+"; numbers to toggle, Enter to continue", "")
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			break
+		}
+		for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 || n > len(entries) {
+				fmt.Fprintf(p.out, "No entry %s\n", field)
+				continue
+			}
+			entries[n-1].excluded = !entries[n-1].excluded
+		}
+		kept = applyPrune(results, entries, config.Dirs)
+	}
+
+	var excluded []*pruneEntry
+	for _, entry := range entries {
+		if entry.excluded {
+			excluded = append(excluded, entry)
+		}
+	}
+	if len(excluded) == 0 {
+		return results, nil
+	}
+	for _, entry := range excluded {
+		if entry.rule.Kind == "dir" {
+			config.IgnoreDirs = append(config.IgnoreDirs, entry.rule.Value)
+		} else {
+			config.IgnoreFiles = append(config.IgnoreFiles, entry.rule.Value)
+		}
+	}
+
+	save, err := p.confirm("Save these filters as a profile?", false)
+	if err != nil || !save {
+		return kept, err
+	}
+	path := config.ConfigFile
+	if path == "" {
+		path = "codexgigantus.json"
+	}
+	if path, err = p.ask("Profile file", path); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		overwrite, err := p.confirm(path+" exists. Overwrite it?", false)
+		if err != nil || !overwrite {
+			return kept, err
+		}
+	}
+	saved := *config
+	saved.Prune = false
+	if err := SaveConfigFile(path, &saved); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(p.out, "Profile written to %s\n", path)
+	return kept, nil
+}
@@ -0,0 +1,73 @@
+// prune_test.go
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func pruneFixture() []FileResult {
+	var results []FileResult
+	for _, path := range []string{"README.md", "docs/README.md", "docs/guide.md", "mydocs/notes.md", "src/docs/api.md", "src/main.go"} {
+		results = append(results, FileResult{Path: path, Source: "fs", TokenCount: 10})
+	}
+	return results
+}
+
+func keptPaths(results []FileResult) string {
+	var paths []string
+	for _, result := range results {
+		paths = append(paths, result.Path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+func TestApplyPruneMatchesOnlyTheListedPath(t *testing.T) {
+	results := pruneFixture()
+	dirs := []string{"."}
+	entries := pruneCandidates(results, dirs)
+	find := func(label string) *pruneEntry {
+		for _, entry := range entries {
+			if entry.label == label {
+				return entry
+			}
+		}
+		t.Fatalf("no entry %s", label)
+		return nil
+	}
+
+	docs := find("docs/")
+	if docs.rule.Value != "/docs" || docs.rule.Files != 2 || docs.rule.Tokens != 20 {
+		t.Errorf("docs/ entry is %+v, want /docs with 2 files and 20 tokens", docs.rule)
+	}
+	docs.excluded = true
+	if got := keptPaths(applyPrune(results, entries, dirs)); got != "README.md,mydocs/notes.md,src/docs/api.md,src/main.go" {
+		t.Errorf("excluding docs/ kept %s", got)
+	}
+
+	docs.excluded = false
+	find("README.md").excluded = true
+	if got := keptPaths(applyPrune(results, entries, dirs)); got != "docs/README.md,docs/guide.md,mydocs/notes.md,src/docs/api.md,src/main.go" {
+		t.Errorf("excluding README.md kept %s", got)
+	}
+}
+
+func TestWalkAppliesAnchoredRules(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, result := range pruneFixture() {
+		fsys[result.Path] = &fstest.MapFile{Data: []byte("x\n")}
+	}
+	config := defaultConfig()
+	config.IgnoreDirs = []string{"/docs"}
+	config.IgnoreFiles = []string{"/README.md"}
+	results, err := ProcessFS(fsys, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keptPaths(results); got != "mydocs/notes.md,src/docs/api.md,src/main.go" {
+		t.Errorf("walk kept %s", got)
+	}
+}
@@ -0,0 +1,114 @@
+// ratelimit.go
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket holding at most one second's worth of
+// tokens. Callers may overdraw it; the debt is paid by whoever comes next.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	// now and sleep are the clock; tests replace them.
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{rate: rate, tokens: rate, last: time.Now(), now: time.Now, sleep: time.Sleep}
+}
+
+// wait blocks until n tokens have been paid for.
+func (l *rateLimiter) wait(n float64) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := l.now()
+	l.tokens = math.Min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= n
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	l.sleep(delay)
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*rateLimiter)
+)
+
+// requestLimiter paces the requests of a network source according to
+// CODEXGIGANTUS_RATE or CODEXGIGANTUS_<SOURCE>_RATE (requests per second).
+// It returns nil, which never waits, when no limit is set.
+func requestLimiter(source string) *rateLimiter {
+	return sourceLimiter(source, "RATE", func(value string) (float64, error) {
+		return strconv.ParseFloat(value, 64)
+	})
+}
+
+// bandwidthLimiter caps the HTTP bytes per second of a source according to
+// CODEXGIGANTUS_BANDWIDTH or CODEXGIGANTUS_<SOURCE>_BANDWIDTH, e.g. 500KB.
+func bandwidthLimiter(source string) *rateLimiter {
+	return sourceLimiter(source, "BANDWIDTH", func(value string) (float64, error) {
+		n, err := parseSize(value)
+		return float64(n), err
+	})
+}
+
+func sourceLimiter(source, name string, parse func(string) (float64, error)) *rateLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	key := source + "/" + name
+	if limiter, ok := limiters[key]; ok {
+		return limiter
+	}
+
+	var limiter *rateLimiter
+	for _, variable := range []string{"CODEXGIGANTUS_" + strings.ToUpper(source) + "_" + name, "CODEXGIGANTUS_" + name} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		if rate, err := parse(value); err == nil && rate > 0 {
+			limiter = newRateLimiter(rate)
+		} else {
+			defaultLogger.Printf("WARNING: ignoring %s=%q", variable, value)
+		}
+		break
+	}
+	limiters[key] = limiter
+	return limiter
+}
+
+// throttledBody paces reads from an HTTP body through a bandwidth limiter.
+type throttledBody struct {
+	io.ReadCloser
+	limiter *rateLimiter
+}
+
+func (b throttledBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.limiter.wait(float64(n))
+	return n, err
+}
+
+func throttle(body io.ReadCloser, limiter *rateLimiter) io.ReadCloser {
+	if body == nil || limiter == nil {
+		return body
+	}
+	return throttledBody{body, limiter}
+}
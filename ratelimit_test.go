@@ -0,0 +1,64 @@
+// ratelimit_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	limiter := newRateLimiter(10)
+	limiter.last = clock
+	limiter.now = func() time.Time { return clock }
+	limiter.sleep = func(d time.Duration) {
+		slept = d
+		clock = clock.Add(d)
+	}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		tokens  float64
+		want    time.Duration
+	}{
+		{name: "full bucket", tokens: 10, want: 0},
+		{name: "empty bucket", tokens: 1, want: 100 * time.Millisecond},
+		{name: "refilled by the wait", tokens: 1, want: 100 * time.Millisecond},
+		{name: "refill is capped at one second", advance: 5 * time.Second, tokens: 10, want: 0},
+		{name: "overdraw", tokens: 25, want: 2500 * time.Millisecond},
+		{name: "debt paid by the next caller", tokens: 5, want: 500 * time.Millisecond},
+	}
+	for _, step := range steps {
+		clock = clock.Add(step.advance)
+		slept = -1
+		limiter.wait(step.tokens)
+		if slept != step.want {
+			t.Errorf("%s: slept %s, want %s", step.name, slept, step.want)
+		}
+	}
+
+	var none *rateLimiter
+	none.wait(1e9)
+}
+
+func TestSourceLimiter(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_RATE", "2")
+	t.Setenv("CODEXGIGANTUS_LIMITTEST_RATE", "5")
+	t.Setenv("CODEXGIGANTUS_BANDWIDTH", "1KB")
+	t.Setenv("CODEXGIGANTUS_BADTEST_BANDWIDTH", "fast")
+
+	if l := requestLimiter("limittest"); l == nil || l.rate != 5 {
+		t.Errorf("per-source rate not used: %+v", l)
+	}
+	if l := requestLimiter("othertest"); l == nil || l.rate != 2 {
+		t.Errorf("global rate not used: %+v", l)
+	}
+	if l := bandwidthLimiter("othertest"); l == nil || l.rate != 1024 {
+		t.Errorf("bandwidth not parsed: %+v", l)
+	}
+	if l := bandwidthLimiter("badtest"); l != nil {
+		t.Errorf("invalid bandwidth gave a limiter: %+v", l)
+	}
+}
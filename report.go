@@ -0,0 +1,252 @@
+// report.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var todoRe = regexp.MustCompile(`\b(TODO|FIXME|XXX|HACK)\b[:(]?\s*(.*)`)
+
+type reportChapter struct {
+	title string
+	body  string
+}
+
+// formatReport composes a single Markdown handover document: summary,
+// directory tree, metrics, open TODOs, the extra sections and every file.
+func formatReport(results []FileResult, sections []Section, config *Config) string {
+	var buffer bytes.Buffer
+
+	chapters := []reportChapter{
+		{"Summary", reportSummary(results, config)},
+		{"Directory tree", "```text\n" + reportTree(results) + "```\n"},
+		{"Metrics", reportMetrics(results)},
+		{"TODOs", reportTodos(results)},
+	}
+	if warnings := config.Warnings.List(); len(warnings) > 0 {
+		chapters = append(chapters, reportChapter{"Warnings", reportWarnings(warnings)})
+	}
+	for _, section := range sections {
+		chapters = append(chapters, reportChapter{section.Title, "```text\n" + strings.TrimRight(section.Content, "\n") + "\n```\n"})
+	}
+	chapters = append(chapters, reportChapter{"Code", reportCode(results)})
+
+	buffer.WriteString("# Repository report\n\n## Contents\n\n")
+	for _, chapter := range chapters {
+		buffer.WriteString(fmt.Sprintf("- [%s](#%s)\n", chapter.title, markdownAnchor(chapter.title)))
+	}
+	for _, chapter := range chapters {
+		buffer.WriteString(fmt.Sprintf("\n## %s\n\n%s", chapter.title, chapter.body))
+	}
+	return buffer.String()
+}
+
+func reportSummary(results []FileResult, config *Config) string {
+	var size int64
+	tokens := 0
+	for _, result := range results {
+		size += result.Size
+		tokens += result.TokenCount
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("- Directories: %s\n", strings.Join(config.Dirs, ", ")))
+	buffer.WriteString(fmt.Sprintf("- Files: %d\n", len(results)))
+	buffer.WriteString(fmt.Sprintf("- Size: %s\n", formatSize(size)))
+	buffer.WriteString(fmt.Sprintf("- Estimated tokens: %d\n", tokens))
+	if config.RunID != "" {
+		buffer.WriteString(fmt.Sprintf("- Run: %s\n", config.RunID))
+	}
+	return buffer.String()
+}
+
+// reportTree draws the included files as an indented tree.
+func reportTree(results []FileResult) string {
+	type node struct {
+		children map[string]*node
+	}
+	root := &node{children: map[string]*node{}}
+	for _, result := range results {
+		current := root
+		for _, part := range strings.Split(filepath.ToSlash(result.Path), "/") {
+			if part == "" || part == "." {
+				continue
+			}
+			next, ok := current.children[part]
+			if !ok {
+				next = &node{children: map[string]*node{}}
+				current.children[part] = next
+			}
+			current = next
+		}
+	}
+
+	var buffer bytes.Buffer
+	var walk func(n *node, prefix string)
+	walk = func(n *node, prefix string) {
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			branch, indent := "├── ", "│   "
+			if i == len(names)-1 {
+				branch, indent = "└── ", "    "
+			}
+			child := n.children[name]
+			if len(child.children) > 0 {
+				name += "/"
+			}
+			buffer.WriteString(prefix + branch + name + "\n")
+			walk(child, prefix+indent)
+		}
+	}
+	walk(root, "")
+	return buffer.String()
+}
+
+// reportMetrics tabulates files, lines and size per language and lists the
+// largest files.
+func reportMetrics(results []FileResult) string {
+	type metrics struct {
+		files, lines int
+		size         int64
+	}
+	byLanguage := make(map[string]*metrics)
+	for _, result := range results {
+		language := result.Language
+		if language == "" {
+			language = "other"
+		}
+		m, ok := byLanguage[language]
+		if !ok {
+			m = &metrics{}
+			byLanguage[language] = m
+		}
+		m.files++
+		m.lines += strings.Count(result.Content, "\n")
+		if result.Content != "" && !strings.HasSuffix(result.Content, "\n") {
+			m.lines++
+		}
+		m.size += result.Size
+	}
+
+	languages := make([]string, 0, len(byLanguage))
+	for language := range byLanguage {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		a, b := byLanguage[languages[i]], byLanguage[languages[j]]
+		if a.lines != b.lines {
+			return a.lines > b.lines
+		}
+		return languages[i] < languages[j]
+	})
+
+	var buffer bytes.Buffer
+	buffer.WriteString("| Language | Files | Lines | Size |\n|---|---:|---:|---:|\n")
+	for _, language := range languages {
+		m := byLanguage[language]
+		buffer.WriteString(fmt.Sprintf("| %s | %d | %d | %s |\n", language, m.files, m.lines, formatSize(m.size)))
+	}
+
+	byClass := make(map[string]*metrics)
+	for _, result := range results {
+		m, ok := byClass[result.Class]
+		if !ok {
+			m = &metrics{}
+			byClass[result.Class] = m
+		}
+		m.files++
+		m.size += result.Size
+	}
+	buffer.WriteString("\n| Class | Files | Size |\n|---|---:|---:|\n")
+	for _, class := range append(fileClasses, "") {
+		if m, ok := byClass[class]; ok {
+			if class == "" {
+				class = "other"
+			}
+			buffer.WriteString(fmt.Sprintf("| %s | %d | %s |\n", class, m.files, formatSize(m.size)))
+		}
+	}
+
+	largest := append([]FileResult(nil), results...)
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+	if len(largest) > 10 {
+		largest = largest[:10]
+	}
+	buffer.WriteString("\nLargest files:\n\n")
+	for _, result := range largest {
+		buffer.WriteString(fmt.Sprintf("- `%s` (%s)\n", filepath.ToSlash(result.Path), formatSize(result.Size)))
+	}
+	return buffer.String()
+}
+
+// reportTodos lists TODO, FIXME, XXX and HACK comments with their location.
+func reportTodos(results []FileResult) string {
+	var buffer bytes.Buffer
+	for _, result := range results {
+		scanner := bufio.NewScanner(strings.NewReader(result.Content))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if match := todoRe.FindStringSubmatch(scanner.Text()); match != nil {
+				buffer.WriteString(fmt.Sprintf("- `%s:%d` %s %s\n", filepath.ToSlash(result.Path), line, match[1], strings.TrimSpace(match[2])))
+			}
+		}
+	}
+	if buffer.Len() == 0 {
+		return "None found.\n"
+	}
+	return buffer.String()
+}
+
+func reportWarnings(warnings []Warning) string {
+	var buffer bytes.Buffer
+	for _, warning := range warnings {
+		if warning.Path != "" {
+			buffer.WriteString(fmt.Sprintf("- **%s** `%s`: %s\n", warning.Kind, filepath.ToSlash(warning.Path), warning.Message))
+		} else {
+			buffer.WriteString(fmt.Sprintf("- **%s**: %s\n", warning.Kind, warning.Message))
+		}
+	}
+	return buffer.String()
+}
+
+func reportCode(results []FileResult) string {
+	var buffer bytes.Buffer
+	for i, result := range results {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		fence := codeFence(result.Content)
+		buffer.WriteString(fmt.Sprintf("### %s\n\n", filepath.ToSlash(result.Path)))
+		buffer.WriteString(fence + fenceLanguage(result.Path) + "\n")
+		buffer.WriteString(result.Content)
+		if result.Content != "" && !strings.HasSuffix(result.Content, "\n") {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(fence + "\n")
+	}
+	return buffer.String()
+}
+
+// markdownAnchor mirrors the heading IDs GitHub generates.
+func markdownAnchor(title string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == ' ':
+			anchor.WriteRune('-')
+		case r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9'):
+			anchor.WriteRune(r)
+		}
+	}
+	return anchor.String()
+}
@@ -0,0 +1,289 @@
+// restore.go
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	repomixFileRe = regexp.MustCompile(`(?s)<file path="([^"]*)">\n(.*?\n)??</file>\n`)
+	placeholderRe = regexp.MustCompile(`\\\{[a-z]+\\\}`)
+	truncatedRe   = regexp.MustCompile(`^\[truncated: \d+ of \d+ bytes shown\]$`)
+)
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	intoFlag := fs.String("into", ".", "Directory the files are restored into")
+	formatFlag := fs.String("format", "auto", "Format of the dump: auto, text, json, csv, tsv, repomix or aider")
+	fileHeaderFlag := fs.String("file-header", "File: {path}", "Per-file header used when the text dump was generated")
+	fileFooterFlag := fs.String("file-footer", "", "Per-file footer used when the text dump was generated")
+	wrapFlag := fs.Int("wrap", 0, "Column the dump was hard-wrapped at with --wrap; the wrapping is undone (-1 restores wrapped lines as they are)")
+	wrapMarkerFlag := fs.String("wrap-marker", " \\", "Continuation marker the dump was wrapped with")
+	dryRunFlag := fs.Bool("dry-run", false, "List the files that would be written")
+
+	// Accept the dump before or after the flags: restore dump.txt -into dir.
+	var dump string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dump, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if dump == "" {
+		dump = fs.Arg(0)
+	}
+	if dump == "" {
+		return errors.New("usage: codexgigantus restore <dump> -into dir")
+	}
+
+	data, err := os.ReadFile(dump)
+	if err != nil {
+		return err
+	}
+
+	files, err := ParseOutput(string(data), *formatFlag, *fileHeaderFlag, *fileFooterFlag)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files found in %s", dump)
+	}
+
+	if *wrapFlag == 0 {
+		if column := detectWrapColumn(files, *wrapMarkerFlag); column > 0 {
+			return fmt.Errorf("%s looks hard-wrapped at %d columns; pass -wrap %d to undo the wrapping, or -wrap -1 to restore the lines as they are", dump, column, column)
+		}
+	}
+
+	// Truncated files would be restored cut short, so they are left alone.
+	var changes []FileChange
+	var refused []string
+	for _, file := range files {
+		if file.Truncated {
+			refused = append(refused, fmt.Sprintf("%s: truncated in the dump (--max-file-size)", file.Path))
+			continue
+		}
+		content := file.Content
+		if *wrapFlag > 0 {
+			content = unwrapLongLines(content, *wrapFlag, *wrapMarkerFlag)
+		}
+		changes = append(changes, FileChange{Path: restorePath(file.Path), Content: content})
+	}
+
+	if !*dryRunFlag {
+		var total int64
+		for _, change := range changes {
+			total += int64(len(change.Content))
+		}
+		if err := ensureDiskSpace(*intoFlag, total); err != nil {
+			return err
+		}
+	}
+
+	report := ApplyChanges(*intoFlag, changes, *dryRunFlag, false)
+	report.Conflicts = append(refused, report.Conflicts...)
+	for _, line := range report.Applied {
+		fmt.Println(line)
+	}
+	for _, line := range report.Conflicts {
+		fmt.Println("SKIPPED:", line)
+	}
+	if len(report.Conflicts) > 0 {
+		return fmt.Errorf("%d of %d files could not be restored", len(report.Conflicts), len(files))
+	}
+	return nil
+}
+
+// ParseOutput reads a dump written by one of the output formats back into
+// path/content pairs. The codemap format carries no content and cannot be
+// restored.
+func ParseOutput(data, format, fileHeader, fileFooter string) ([]FileResult, error) {
+	if format == "auto" {
+		format = detectOutputFormat(data, fileHeader)
+	}
+
+	switch format {
+	case "json":
+		var parsed jsonOutput
+		if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+			return nil, err
+		}
+		return parsed.Files, nil
+	case "csv":
+		return parseDelimited(data, ',')
+	case "tsv":
+		return parseDelimited(data, '\t')
+	case "repomix":
+		return parseRepomix(data), nil
+	case "aider":
+		return parseAider(data), nil
+	case "text":
+		return parseText(data, fileHeader, fileFooter)
+	}
+	return nil, fmt.Errorf("cannot restore format %q", format)
+}
+
+func detectOutputFormat(data, fileHeader string) string {
+	switch {
+	case strings.HasPrefix(data, "{") && json.Valid([]byte(data)):
+		return "json"
+	case strings.HasPrefix(data, "path,content\n"):
+		return "csv"
+	case strings.HasPrefix(data, "path\tcontent\n"):
+		return "tsv"
+	case strings.Contains(data, "\n<files>\n"):
+		return "repomix"
+	}
+
+	// Aider dumps start with a file name directly followed by a fence; a
+	// text dump of a file opening with a fence starts with the header.
+	lines := strings.SplitN(strings.TrimLeft(data, "\n"), "\n", 3)
+	if len(lines) > 1 && lines[0] != "" && strings.HasPrefix(lines[1], "```") && !textDelimiter(fileHeader, "")(lines[0]) {
+		return "aider"
+	}
+	return "text"
+}
+
+func parseDelimited(data string, comma rune) ([]FileResult, error) {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.Comma = comma
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileResult
+	for i, record := range records {
+		if i == 0 || len(record) < 2 {
+			continue
+		}
+		files = append(files, FileResult{Path: record[0], Content: record[1]})
+	}
+	return files, nil
+}
+
+func parseRepomix(data string) []FileResult {
+	var files []FileResult
+	for _, match := range repomixFileRe.FindAllStringSubmatch(data, -1) {
+		files = append(files, FileResult{Path: unescapeXMLAttr(match[1]), Content: unescapeDelimiterLines(match[2], repomixDelimiter)})
+	}
+	return files
+}
+
+// parseAider reads the blocks formatAider writes: a path line, the opening
+// fence, the content and a closing fence of the same length. Unlike
+// ParseResponse it accepts any path, including LICENSE or Makefile.
+func parseAider(data string) []FileResult {
+	var files []FileResult
+	lines := strings.Split(data, "\n")
+
+	for i := 0; i+1 < len(lines); i++ {
+		if lines[i] == "" || !strings.HasPrefix(lines[i+1], "```") {
+			continue
+		}
+		opening := lines[i+1]
+		fence := opening[:len(opening)-len(strings.TrimLeft(opening, "`"))]
+
+		var content []string
+		j := i + 2
+		for ; j < len(lines) && !isClosingFence(lines[j], fence); j++ {
+			content = append(content, lines[j])
+		}
+		file := FileResult{Path: lines[i]}
+		if len(content) > 0 {
+			file.Content = strings.Join(content, "\n") + "\n"
+		}
+		files = append(files, file)
+		i = j
+	}
+	return files
+}
+
+// parseText splits a text dump on lines matching the file header. After the
+// content the formatter writes an optional footer line and a blank line.
+func parseText(data, fileHeader, fileFooter string) ([]FileResult, error) {
+	if !strings.Contains(fileHeader, "{path}") {
+		return nil, errors.New("the file header must contain {path} to be restorable")
+	}
+	headerRe, err := delimiterPattern(fileHeader)
+	if err != nil {
+		return nil, err
+	}
+	var footerRe *regexp.Regexp
+	if fileFooter != "" {
+		if footerRe, err = delimiterPattern(fileFooter); err != nil {
+			return nil, err
+		}
+	}
+
+	isDelimiter := textDelimiter(fileHeader, fileFooter)
+
+	var files []FileResult
+	var current *FileResult
+	var content []string
+
+	flush := func(last bool) {
+		if current == nil {
+			return
+		}
+		text := strings.TrimSuffix(strings.Join(content, "\n"), "\n")
+		if last {
+			// The end of the dump may carry extra newlines from printing.
+			if text = strings.TrimRight(text, "\n"); text != "" {
+				text += "\n"
+			}
+		}
+		if footerRe != nil {
+			body := strings.TrimSuffix(text, "\n")
+			if i := strings.LastIndex(body, "\n"); i >= 0 && footerRe.MatchString(body[i+1:]) {
+				text = body[:i]
+			}
+		}
+		// formatText notes a truncation on the line after the content.
+		if body := strings.TrimSuffix(text, "\n"); truncatedRe.MatchString(body[strings.LastIndex(body, "\n")+1:]) {
+			current.Truncated = true
+			text = body[:max(strings.LastIndex(body, "\n"), 0)]
+		}
+		current.Content = unescapeDelimiterLines(text, isDelimiter)
+		files = append(files, *current)
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		if match := headerRe.FindStringSubmatch(line); match != nil {
+			flush(false)
+			current = &FileResult{Path: match[1]}
+			content = nil
+			continue
+		}
+		if current != nil {
+			content = append(content, line)
+		}
+	}
+	flush(true)
+
+	return files, nil
+}
+
+// restorePath strips source tags and makes absolute paths relative, so
+// every file lands inside the target directory.
+func restorePath(path string) string {
+	if strings.HasPrefix(path, "git:") {
+		if i := strings.Index(path[4:], ":"); i >= 0 {
+			path = path[4+i+1:]
+		}
+	}
+	for _, tag := range []string{"fs:", "dep:", "std:"} {
+		path = strings.TrimPrefix(path, tag)
+	}
+	if len(path) > 1 && path[1] == ':' {
+		path = path[2:]
+	}
+	return strings.TrimLeft(strings.ReplaceAll(path, `\`, "/"), "/")
+}
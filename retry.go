@@ -0,0 +1,171 @@
+// retry.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryPolicy controls how often a network call is attempted and how long
+// to wait in between; the wait doubles after every failure.
+type retryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Timeout    time.Duration // per attempt; 0 keeps the client's own timeout
+}
+
+var defaultRetryPolicy = retryPolicy{Attempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// sourceRetryPolicies holds the built-in per-source differences: a local
+// Ollama that is not running should be reported at once, while reads from
+// a network filesystem (--network-fs) recover within seconds.
+var sourceRetryPolicies = map[string]retryPolicy{
+	"ollama": {Attempts: 1, Backoff: time.Second, MaxBackoff: 30 * time.Second},
+	"fs":     {Attempts: 5, Backoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second},
+}
+
+// retryPolicyFor returns the policy of a network source (github, git,
+// ollama or sink). CODEXGIGANTUS_RETRIES, CODEXGIGANTUS_RETRY_BACKOFF and
+// CODEXGIGANTUS_TIMEOUT apply to all sources; CODEXGIGANTUS_<SOURCE>_RETRIES
+// and friends override them for one.
+func retryPolicyFor(source string) retryPolicy {
+	policy, ok := sourceRetryPolicies[source]
+	if !ok {
+		policy = defaultRetryPolicy
+	}
+
+	for _, prefix := range []string{"CODEXGIGANTUS_", "CODEXGIGANTUS_" + strings.ToUpper(source) + "_"} {
+		if value := os.Getenv(prefix + "RETRIES"); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				policy.Attempts = n + 1
+			} else {
+				defaultLogger.Printf("WARNING: ignoring %sRETRIES=%q, expected a number", prefix, value)
+			}
+		}
+		// A backoff has to be positive for the jitter; a timeout of 0
+		// keeps the client's own.
+		for name, target := range map[string]*time.Duration{"RETRY_BACKOFF": &policy.Backoff, "TIMEOUT": &policy.Timeout} {
+			if value := os.Getenv(prefix + name); value != "" {
+				if d, err := time.ParseDuration(value); err == nil && (d > 0 || d == 0 && name == "TIMEOUT") {
+					*target = d
+				} else {
+					defaultLogger.Printf("WARNING: ignoring %s%s=%q, expected a duration such as 2s", prefix, name, value)
+				}
+			}
+		}
+	}
+	return policy
+}
+
+// retryableError marks a failure worth another attempt.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string { return e.err.Error() }
+func (e retryableError) Unwrap() error { return e.err }
+
+func retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err}
+}
+
+// retrySleep waits between attempts; tests replace it.
+var retrySleep = time.Sleep
+
+// retry runs fn until it succeeds, fails with an error not marked
+// retryable, or the policy's attempts are used up.
+func retry(source string, fn func() error) error {
+	policy := retryPolicyFor(source)
+	wait := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var retry retryableError
+		if err == nil || !errors.As(err, &retry) {
+			return err
+		}
+		if attempt >= policy.Attempts {
+			if attempt > 1 {
+				return fmt.Errorf("%w (after %d attempts)", retry.err, attempt)
+			}
+			return retry.err
+		}
+
+		// Jitter keeps parallel workers from retrying in lockstep.
+		sleep := wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		defaultLogger.Printf("WARNING: %s: attempt %d of %d failed (%v), retrying in %s", source, attempt, policy.Attempts, retry.err, formatDuration(sleep))
+		retrySleep(sleep)
+		if wait *= 2; wait > policy.MaxBackoff {
+			wait = policy.MaxBackoff
+		}
+	}
+}
+
+// doHTTP sends req under the retry policy of source. Network errors, 429
+// and 5xx responses are retried when req is idempotent (see idempotent);
+// any other response is returned as is.
+func doHTTP(client *http.Client, req *http.Request, source string) (*http.Response, error) {
+	if timeout := retryPolicyFor(source).Timeout; timeout > 0 {
+		withTimeout := *client
+		withTimeout.Timeout = timeout
+		client = &withTimeout
+	}
+
+	requests, bandwidth := requestLimiter(source), bandwidthLimiter(source)
+	// A POST that failed after reaching the server may have taken effect;
+	// sending it again could upload a file twice.
+	mark := retryable
+	if !idempotent(req) {
+		mark = func(err error) error { return err }
+	}
+
+	var resp *http.Response
+	err := retry(source, func() error {
+		attempt := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			attempt.Body = throttle(body, bandwidth)
+		}
+
+		requests.wait(1)
+		r, err := client.Do(attempt)
+		if err != nil {
+			return mark(err)
+		}
+		// An exhausted quota with a reset time is left to the caller;
+		// githubGet waits for the reset instead of backing off blindly.
+		quotaReset := r.Header.Get("X-RateLimit-Remaining") == "0"
+		if (r.StatusCode == http.StatusTooManyRequests && !quotaReset) || r.StatusCode >= 500 {
+			r.Body.Close()
+			return mark(fmt.Errorf("%s %s: unexpected status %s", req.Method, req.URL.Host, r.Status))
+		}
+		r.Body = throttle(r.Body, bandwidth)
+		resp = r
+		return nil
+	})
+	return resp, err
+}
+
+// idempotent reports whether sending req twice has the effect of sending it
+// once: the methods HTTP defines as idempotent, or any request carrying an
+// Idempotency-Key header the server deduplicates on.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
@@ -0,0 +1,138 @@
+// retry_test.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recordSleeps replaces retrySleep for the duration of a test.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	t.Cleanup(func() { retrySleep = time.Sleep })
+	retrySleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return &sleeps
+}
+
+func TestRetryAttempts(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_RETRIES", "2")
+	sleeps := recordSleeps(t)
+
+	calls := 0
+	err := retry("test", func() error {
+		calls++
+		return retryable(errors.New("down"))
+	})
+	if calls != 3 || len(*sleeps) != 2 {
+		t.Errorf("got %d calls and %d sleeps, want 3 and 2", calls, len(*sleeps))
+	}
+	if err == nil || err.Error() != "down (after 3 attempts)" {
+		t.Errorf("got %v", err)
+	}
+
+	calls = 0
+	err = retry("test", func() error {
+		if calls++; calls < 2 {
+			return retryable(errors.New("down"))
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("got %v after %d calls, want success after 2", err, calls)
+	}
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_RETRIES", "5")
+	t.Setenv("CODEXGIGANTUS_RETRY_BACKOFF", "10s")
+	sleeps := recordSleeps(t)
+
+	retry("test", func() error { return retryable(errors.New("down")) })
+	max := defaultRetryPolicy.MaxBackoff
+	waits := []time.Duration{10 * time.Second, 20 * time.Second, max, max, max}
+	if len(*sleeps) != len(waits) {
+		t.Fatalf("got %d sleeps, want %d", len(*sleeps), len(waits))
+	}
+	for i, sleep := range *sleeps {
+		if sleep < waits[i]/2 || sleep > waits[i] {
+			t.Errorf("sleep %d is %s, want between %s and %s", i+1, sleep, waits[i]/2, waits[i])
+		}
+	}
+}
+
+func TestRetryPassesOtherErrorsThrough(t *testing.T) {
+	sleeps := recordSleeps(t)
+	notFound := errors.New("404 not found")
+	calls := 0
+	err := retry("test", func() error {
+		calls++
+		return notFound
+	})
+	if err != notFound || calls != 1 || len(*sleeps) != 0 {
+		t.Errorf("got %v after %d calls and %d sleeps, want the error at once", err, calls, len(*sleeps))
+	}
+}
+
+func TestRetryPolicyRejectsNonPositiveBackoff(t *testing.T) {
+	for _, value := range []string{"-2s", "0s", "soon"} {
+		t.Setenv("CODEXGIGANTUS_RETRY_BACKOFF", value)
+		if got := retryPolicyFor("test").Backoff; got != defaultRetryPolicy.Backoff {
+			t.Errorf("CODEXGIGANTUS_RETRY_BACKOFF=%s gave a backoff of %s", value, got)
+		}
+	}
+	t.Setenv("CODEXGIGANTUS_RETRY_BACKOFF", "")
+	t.Setenv("CODEXGIGANTUS_TIMEOUT", "0")
+	if got := retryPolicyFor("test").Timeout; got != 0 {
+		t.Errorf("CODEXGIGANTUS_TIMEOUT=0 gave %s", got)
+	}
+}
+
+func TestDoHTTPRetries(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_RETRIES", "3")
+	recordSleeps(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/flaky":
+			if calls < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		method, path string
+		status       int
+		calls        int
+	}{
+		{http.MethodGet, "/flaky", http.StatusOK, 3},
+		{http.MethodGet, "/missing", http.StatusNotFound, 1},
+		{http.MethodPost, "/down", 0, 1},
+	}
+	for _, tc := range cases {
+		calls = 0
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, nil)
+		resp, err := doHTTP(server.Client(), req, "test")
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+			resp.Body.Close()
+		}
+		if status != tc.status || calls != tc.calls {
+			t.Errorf("%s %s: got status %d after %d calls (%v), want %d after %d", tc.method, tc.path, status, calls, err, tc.status, tc.calls)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// routes.go
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// route is one HTTP route registration found in the source.
+type route struct {
+	Method, Path, Handler string
+	File                  string
+	Line                  int
+}
+
+// goRouteMethods maps router method names (net/http, chi, gin, echo) to the
+// HTTP method they register; "" means any method.
+var goRouteMethods = map[string]string{
+	"HandleFunc": "", "Handle": "", "Any": "", "Mount": "", "Route": "", "Method": "", "MethodFunc": "",
+	"GET": "GET", "POST": "POST", "PUT": "PUT", "PATCH": "PATCH", "DELETE": "DELETE", "HEAD": "HEAD", "OPTIONS": "OPTIONS",
+	"Get": "GET", "Post": "POST", "Put": "PUT", "Patch": "PATCH", "Delete": "DELETE", "Head": "HEAD", "Options": "OPTIONS",
+}
+
+var (
+	expressRouteRe = regexp.MustCompile(`\b(?:app|router|server|api)\.(get|post|put|patch|delete|head|options|all|use)\(\s*['"` + "`" + `]([^'"` + "`" + `]+)['"` + "`" + `]\s*,\s*([^\n]*)`)
+	pythonRouteRe  = regexp.MustCompile(`^\s*@\w+\.(get|post|put|patch|delete|head|options|route|api_route)\(\s*['"]([^'"]+)['"]([^\n]*)`)
+	pythonMethodRe = regexp.MustCompile(`methods\s*=\s*\[([^\]]*)\]`)
+	pythonDefRe    = regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`)
+	jsHandlerRe    = regexp.MustCompile(`^[\w.]+`)
+)
+
+// findRoutes collects the route registrations of Go, JavaScript/TypeScript
+// and Python files. Go is parsed; the others are matched heuristically.
+func findRoutes(results []FileResult) []route {
+	var routes []route
+	for _, result := range results {
+		switch result.Language {
+		case "go":
+			routes = append(routes, goRoutes(result)...)
+		case "javascript", "typescript", "jsx", "tsx":
+			routes = append(routes, expressRoutes(result)...)
+		case "python":
+			routes = append(routes, pythonRoutes(result)...)
+		}
+	}
+	return routes
+}
+
+func goRoutes(result FileResult) []route {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", result.Content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var routes []route
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		method, ok := goRouteMethods[sel.Sel.Name]
+		if !ok {
+			return true
+		}
+		pathArg, handlerArg := call.Args[0], call.Args[len(call.Args)-1]
+		// chi's Method("GET", "/path", handler).
+		if strings.HasPrefix(sel.Sel.Name, "Method") && len(call.Args) == 3 {
+			method = stringLiteral(call.Args[0])
+			pathArg = call.Args[1]
+		}
+		path := stringLiteral(pathArg)
+		if !strings.HasPrefix(path, "/") && !strings.Contains(path, " /") {
+			return true
+		}
+		// Go 1.22 patterns carry the method: "GET /users/{id}".
+		if m, p, ok := strings.Cut(path, " "); ok && method == "" {
+			method, path = m, strings.TrimSpace(p)
+		}
+		routes = append(routes, route{
+			Method:  method,
+			Path:    path,
+			Handler: exprString(fset, handlerArg),
+			File:    result.Path,
+			Line:    fset.Position(call.Pos()).Line,
+		})
+		return true
+	})
+	return routes
+}
+
+func stringLiteral(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// exprString prints a handler expression, shortening function literals.
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	if _, ok := expr.(*ast.FuncLit); ok {
+		return "func literal"
+	}
+	var buffer strings.Builder
+	if err := printer.Fprint(&buffer, fset, expr); err != nil {
+		return "?"
+	}
+	return ellipsize(strings.Join(strings.Fields(buffer.String()), " "), 60)
+}
+
+func expressRoutes(result FileResult) []route {
+	var routes []route
+	for i, line := range strings.Split(result.Content, "\n") {
+		for _, m := range expressRouteRe.FindAllStringSubmatch(line, -1) {
+			method := strings.ToUpper(m[1])
+			if method == "ALL" || method == "USE" {
+				method = ""
+			}
+			handler := "inline"
+			if h := jsHandlerRe.FindString(strings.TrimSpace(m[3])); h != "" && h != "async" && h != "function" {
+				handler = h
+			}
+			routes = append(routes, route{Method: method, Path: m[2], Handler: handler, File: result.Path, Line: i + 1})
+		}
+	}
+	return routes
+}
+
+func pythonRoutes(result FileResult) []route {
+	var routes []route
+	lines := strings.Split(result.Content, "\n")
+	for i, line := range lines {
+		m := pythonRouteRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		method := strings.ToUpper(m[1])
+		if method == "ROUTE" || method == "API_ROUTE" {
+			method = ""
+			if methods := pythonMethodRe.FindStringSubmatch(m[3]); methods != nil {
+				method = strings.ToUpper(strings.NewReplacer(`"`, "", "'", "", " ", "").Replace(methods[1]))
+			}
+		}
+		handler := "?"
+		for _, next := range lines[i+1:] {
+			if def := pythonDefRe.FindStringSubmatch(next); def != nil {
+				handler = def[1]
+				break
+			}
+		}
+		routes = append(routes, route{Method: method, Path: m[2], Handler: handler, File: result.Path, Line: i + 1})
+	}
+	return routes
+}
+
+// routesSection renders the routes as a METHOD/PATH/HANDLER table.
+func routesSection(results []FileResult) (Section, bool) {
+	routes := findRoutes(results)
+	if len(routes) == 0 {
+		return Section{}, false
+	}
+
+	var buffer strings.Builder
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "METHOD\tPATH\tHANDLER\tLOCATION")
+	for _, r := range routes {
+		method := r.Method
+		if method == "" {
+			method = "*"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s:%d\n", method, r.Path, r.Handler, filepath.ToSlash(r.File), r.Line)
+	}
+	writer.Flush()
+	return Section{Title: "API routes", Content: buffer.String()}, true
+}
@@ -0,0 +1,27 @@
+// runid.go
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// newRunID names one processing run so its log lines, banner and summary
+// can be matched up. CODEXGIGANTUS_RUN_ID lets a caller that starts several
+// runs (a scheduler, a GUI) hand them all its own ID.
+func newRunID() string {
+	if id := os.Getenv("CODEXGIGANTUS_RUN_ID"); id != "" {
+		return id
+	}
+	now := time.Now()
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// Without a random source the process ID and the clock still keep
+		// concurrent runs apart.
+		return now.UTC().Format("20060102T150405Z") + fmt.Sprintf("-%06x", (os.Getpid()<<12^now.Nanosecond())&0xffffff)
+	}
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
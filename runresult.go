@@ -0,0 +1,113 @@
+// runresult.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RunResult describes a finished run in one shape for every entry point:
+// the CLI prints it with --format json-result, Run returns it and batch
+// writes one per entry to summary.json, so automation reads them all alike.
+type RunResult struct {
+	RunID string `json:"run_id"`
+	// Name is the batch entry the run belongs to.
+	Name string `json:"name,omitempty"`
+	// Status is "ok", "unchanged" (the output file already held the
+	// output) or "failed".
+	Status     string    `json:"status"`
+	Started    time.Time `json:"started"`
+	DurationMS int64     `json:"duration_ms"`
+	Stats      RunStats  `json:"stats"`
+	Artifacts  []string  `json:"artifacts,omitempty"`
+	Warnings   []Warning `json:"warnings,omitempty"`
+	Errors     []string  `json:"errors,omitempty"`
+	// Log holds the lines of a Config.Logger made with NewCaptureLogger.
+	Log []string `json:"log,omitempty"`
+
+	// Sections and Files carry the context itself in --format json-result,
+	// in the layout of the json format.
+	Sections []Section    `json:"sections,omitempty"`
+	Files    []FileResult `json:"files,omitempty"`
+}
+
+// RunStats sums up the files of a run; Bytes is their size on disk.
+type RunStats struct {
+	Files  int   `json:"files"`
+	Bytes  int64 `json:"bytes"`
+	Tokens int   `json:"tokens"`
+}
+
+// newRunResult describes a run of config that included results and ended
+// with err, which may be nil.
+func newRunResult(results []FileResult, config *Config, err error) *RunResult {
+	result := &RunResult{
+		RunID:    config.RunID,
+		Status:   "ok",
+		Started:  config.started,
+		Stats:    RunStats{Files: len(results), Tokens: totalTokens(results)},
+		Warnings: config.Warnings.List(),
+	}
+	if config.Logger != nil {
+		result.Log = config.Logger.Entries()
+	}
+	if !config.started.IsZero() {
+		result.DurationMS = time.Since(config.started).Milliseconds()
+	}
+	for _, file := range results {
+		result.Stats.Bytes += file.Size
+	}
+	if err != nil {
+		result.Status = "failed"
+		result.Errors = []string{err.Error()}
+	}
+	return result
+}
+
+func (r *RunResult) String() string {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("{\"status\": \"failed\", \"errors\": [%q]}\n", err.Error())
+	}
+	return string(data) + "\n"
+}
+
+// formatRunResult is the json-result format: the run's RunResult with the
+// files and sections included.
+func formatRunResult(results []FileResult, sections []Section, config *Config) string {
+	result := newRunResult(results, config, nil)
+	if config.Save {
+		result.Artifacts = []string{config.OutputFile}
+	}
+	result.Sections = sections
+	result.Files = results
+	return result.String()
+}
+
+// Run runs the pipeline like Generate, saves the output when config.Save
+// is set, records the --delta manifest once the output is saved, and
+// describes the run. The RunResult is returned even when the
+// run fails.
+func Run(config *Config) (string, *RunResult, error) {
+	if config.started.IsZero() {
+		config.started = time.Now()
+	}
+	output, results, included, err := generate(config)
+	changed := true
+	if err == nil && config.Save {
+		changed, err = saveIfChanged(output, config.OutputFile, config)
+	}
+	if err == nil && config.Delta {
+		recordManifest(included, config)
+	}
+
+	result := newRunResult(results, config, err)
+	if err == nil && config.Save {
+		result.Artifacts = []string{config.OutputFile}
+		if !changed {
+			result.Status = "unchanged"
+		}
+	}
+	return output, result, err
+}
@@ -0,0 +1,45 @@
+// runresult_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunResultCarriesCapturedLog(t *testing.T) {
+	t.Setenv("CODEXGIGANTUS_HISTORY", filepath.Join(t.TempDir(), "history.jsonl"))
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("main.go", filepath.Join(root, "shortcut.go")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	config := defaultConfig()
+	config.Dirs = []string{root}
+	config.Debug = true
+	config.Logger = NewCaptureLogger()
+	_, result, err := Run(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Kind != "duplicate" {
+		t.Errorf("warnings %+v, want one duplicate", result.Warnings)
+	}
+	log := strings.Join(result.Log, "\n")
+	if !strings.Contains(log, "DEBUG") || !strings.Contains(log, "WARNING") {
+		t.Errorf("log lacks the debug and warning lines:\n%s", log)
+	}
+	if !strings.Contains(result.String(), `"log": [`) {
+		t.Errorf("log not in the JSON envelope:\n%s", result)
+	}
+
+	config = defaultConfig()
+	config.Dirs = []string{root}
+	if _, result, _ := Run(config); result.Log != nil {
+		t.Errorf("log captured without a capture logger: %v", result.Log)
+	}
+}
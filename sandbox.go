@@ -0,0 +1,115 @@
+// sandbox.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// applyInSandbox applies the changes to a temporary copy of root and runs
+// checkCmd there. The real tree is only touched when both succeed.
+func applyInSandbox(root string, changes []FileChange, checkCmd string, backup bool) (applyReport, error) {
+	sandbox, err := makeTempDir("sandbox-")
+	if err != nil {
+		return applyReport{}, err
+	}
+	defer removeTempDir(sandbox)
+
+	if err := copyTree(root, sandbox); err != nil {
+		return applyReport{}, fmt.Errorf("copying %s into sandbox: %w", root, err)
+	}
+
+	report := ApplyChanges(sandbox, changes, false, false)
+	if len(report.Conflicts) > 0 {
+		return report, fmt.Errorf("%d of %d changes could not be applied in the sandbox; nothing was promoted", len(report.Conflicts), len(changes))
+	}
+
+	if output, err := runShell(sandbox, checkCmd); err != nil {
+		fmt.Printf("Check command %q failed in the sandbox:\n%s\n", checkCmd, output)
+		return applyReport{}, fmt.Errorf("sandbox check failed (%v); nothing was promoted", err)
+	}
+
+	return ApplyChanges(root, changes, false, backup), nil
+}
+
+func runShell(dir, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// copyTree copies regular files and directories from src to dst, skipping
+// the .git directory.
+// copyTree copies regular files, directories and symlinks from src to dst,
+// skipping the .git directory. Absolute links into src are pointed at the
+// copy, so the check command does not read the real tree.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return copySymlink(src, dst, path, target)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copySymlink(src, dst, path, target string) error {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+	if abs, err := filepath.Abs(src); err == nil && filepath.IsAbs(link) && isInside(abs, link) {
+		rel, _ := filepath.Rel(abs, link)
+		link = filepath.Join(dst, rel)
+	}
+	if err := os.Symlink(link, target); err != nil {
+		defaultLogger.Printf("WARNING: sandbox: skipping symlink %s: %v", path, err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
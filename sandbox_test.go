@@ -0,0 +1,54 @@
+// sandbox_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyTreeKeepsSymlinks(t *testing.T) {
+	src, dst, outside := t.TempDir(), t.TempDir(), t.TempDir()
+	for _, dir := range []string{"lib", ".git"} {
+		if err := os.Mkdir(filepath.Join(src, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"lib/a.go", ".git/HEAD"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"relative.go": "lib/a.go",
+		"absolute.go": filepath.Join(src, "lib", "a.go"),
+		"outside":     outside,
+		"vendor":      "lib",
+	}
+	for name, link := range links {
+		if err := os.Symlink(link, filepath.Join(src, name)); err != nil {
+			t.Skip("symlinks unsupported:", err)
+		}
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"relative.go": "lib/a.go",
+		"absolute.go": filepath.Join(dst, "lib", "a.go"),
+		"outside":     outside,
+		"vendor":      "lib",
+	}
+	for name, link := range want {
+		if got, err := os.Readlink(filepath.Join(dst, name)); err != nil || got != link {
+			t.Errorf("%s links to %q (%v), want %q", name, got, err, link)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "vendor", "a.go")); err != nil || string(data) != "x\n" {
+		t.Errorf("vendor/a.go = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".git")); !os.IsNotExist(err) {
+		t.Errorf(".git copied: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+// sections.go
+package main
+
+import (
+	"strings"
+)
+
+// BuildSections gathers the optional context sections enabled in the config.
+// With config.Coverage set it also fills in the Coverage of each result.
+func BuildSections(results []FileResult, config *Config) ([]Section, error) {
+	var sections []Section
+
+	if config.BuildSection {
+		if section, ok := buildSection(results); ok {
+			sections = append(sections, section)
+		}
+	}
+
+	if config.Routes {
+		if section, ok := routesSection(results); ok {
+			sections = append(sections, section)
+		}
+	}
+
+	if config.EnvVars {
+		if section, ok := envVarsSection(results, config); ok {
+			sections = append(sections, section)
+		}
+	}
+
+	if config.ModuleSummaries {
+		sections = append(sections, moduleSections(results)...)
+	}
+
+	if config.GitLog > 0 {
+		var logs []string
+		for _, dir := range canonicalDirs(config.Dirs) {
+			log, err := recentCommits(dir, config.GitLog, includedPaths(results, dir, config.GitLogFiles))
+			if err != nil {
+				return nil, err
+			}
+			if log != "" {
+				logs = append(logs, log)
+			}
+		}
+		if len(logs) > 0 {
+			sections = append(sections, Section{Title: "Recent commits", Content: strings.Join(logs, "\n")})
+		}
+	}
+
+	if config.Coverage != "" {
+		section, err := coverageSection(results, config)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+
+	return sections, nil
+}
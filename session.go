@@ -0,0 +1,164 @@
+// session.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Session records every round trip of an LLM-assisted change: the context
+// that was sent, the question, the model's answer and the patches applied.
+type Session struct {
+	Name       string      `json:"name"`
+	Created    time.Time   `json:"created"`
+	Config     *Config     `json:"config"`
+	Iterations []Iteration `json:"iterations"`
+}
+
+type Iteration struct {
+	Started   time.Time `json:"started"`
+	RunID     string    `json:"run_id,omitempty"`
+	Question  string    `json:"question"`
+	Context   string    `json:"context"`
+	Response  string    `json:"response,omitempty"`
+	Applied   []string  `json:"applied,omitempty"`
+	Conflicts []string  `json:"conflicts,omitempty"`
+}
+
+func runSession(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: codexgigantus session new|continue [flags]")
+	}
+
+	switch args[0] {
+	case "new":
+		return runSessionNew(args[1:])
+	case "continue":
+		return runSessionContinue(args[1:])
+	default:
+		return fmt.Errorf("unknown session command: %s", args[0])
+	}
+}
+
+func runSessionNew(args []string) error {
+	fs := flag.NewFlagSet("session new", flag.ExitOnError)
+	nameFlag := fs.String("name", "", "Session name; the session is stored in <name>.session.json")
+	questionFlag := fs.String("question", "", "Question to ask about the generated context")
+
+	config, err := ParseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *nameFlag == "" {
+		return errors.New("-name is required")
+	}
+
+	path := *nameFlag + ".session.json"
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("session %s already exists; use session continue", path)
+	}
+
+	session := &Session{Name: *nameFlag, Created: time.Now(), Config: config}
+	if err := session.startIteration(*questionFlag); err != nil {
+		return err
+	}
+	return session.save(path)
+}
+
+func runSessionContinue(args []string) error {
+	fs := flag.NewFlagSet("session continue", flag.ExitOnError)
+	fileFlag := fs.String("file", "", "Session file to continue")
+	responseFlag := fs.String("response", "", "File with the model's answer to the last question ('-' reads stdin)")
+	applyFlag := fs.Bool("apply", false, "Apply diffs and file blocks from the response to the first session directory")
+	questionFlag := fs.String("question", "", "Start a new iteration with this question and freshly generated context")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *fileFlag == "" {
+		return errors.New("-file is required")
+	}
+
+	session, err := loadSession(*fileFlag)
+	if err != nil {
+		return err
+	}
+
+	if *responseFlag != "" {
+		last := &session.Iterations[len(session.Iterations)-1]
+		if last.Response, err = readResponse(*responseFlag); err != nil {
+			return err
+		}
+
+		if *applyFlag {
+			report := ApplyChanges(session.Config.Dirs[0], ParseResponse(last.Response), false, true)
+			last.Applied = report.Applied
+			last.Conflicts = report.Conflicts
+			for _, line := range report.Applied {
+				fmt.Println(line)
+			}
+			for _, line := range report.Conflicts {
+				fmt.Println("CONFLICT:", line)
+			}
+		}
+	}
+
+	if *questionFlag != "" {
+		if err := session.startIteration(*questionFlag); err != nil {
+			return err
+		}
+	}
+
+	return session.save(*fileFlag)
+}
+
+// startIteration regenerates the context with the session's settings and
+// prints the prompt to send to the model.
+func (s *Session) startIteration(question string) error {
+	s.Config.RunID = newRunID()
+	context, _, err := Generate(s.Config)
+	if err != nil {
+		return err
+	}
+
+	s.Iterations = append(s.Iterations, Iteration{
+		Started:  time.Now(),
+		RunID:    s.Config.RunID,
+		Question: question,
+		Context:  context,
+	})
+
+	fmt.Println(context)
+	if question != "" {
+		fmt.Printf("\nQuestion: %s\n", question)
+	}
+	return nil
+}
+
+func loadSession(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Settings missing from older session files keep their defaults.
+	session := &Session{Config: defaultConfig()}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("parsing session %s: %w", path, err)
+	}
+	if session.Config == nil || len(session.Config.Dirs) == 0 || len(session.Iterations) == 0 {
+		return nil, fmt.Errorf("session %s is incomplete", path)
+	}
+	return session, nil
+}
+
+func (s *Session) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
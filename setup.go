@@ -0,0 +1,192 @@
+// setup.go
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// projectPreset is a starting point for the filters of a project type.
+type projectPreset struct {
+	IncludeExts []string
+	IgnoreDirs  []string
+	IgnoreFiles []string
+}
+
+var projectPresets = map[string]projectPreset{
+	"go": {
+		IncludeExts: []string{"go", "mod", "md"},
+		IgnoreDirs:  []string{".git", "vendor"},
+		IgnoreFiles: []string{"go.sum"},
+	},
+	"node": {
+		IncludeExts: []string{"js", "jsx", "ts", "tsx", "json", "md"},
+		IgnoreDirs:  []string{".git", "node_modules", "dist", "build", "coverage"},
+		IgnoreFiles: []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
+	},
+	"python": {
+		IncludeExts: []string{"py", "toml", "cfg", "md"},
+		IgnoreDirs:  []string{".git", "__pycache__", ".venv", "venv", ".tox", "build", "dist"},
+	},
+	"generic": {
+		IgnoreDirs: []string{".git"},
+	},
+}
+
+func projectPresetNames() []string {
+	var names []string
+	for name := range projectPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// prompter asks questions on out and reads the answers from in.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask returns the answer to question, or def when the answer is empty.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errors.New("setup aborted")
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// choose asks until the answer is one of choices.
+func (p *prompter) choose(question, def string, choices []string) (string, error) {
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, ", ")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, choice := range choices {
+			if answer == choice {
+				return answer, nil
+			}
+		}
+		fmt.Fprintf(p.out, "Please answer one of: %s\n", strings.Join(choices, ", "))
+	}
+}
+
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := p.ask(question+" ("+hint+")", "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// runSetup walks a first-time user through the main settings and writes
+// them as a profile that can be passed to --config.
+func runSetup(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ExitOnError)
+	outFlag := fs.String("out", "codexgigantus.json", "Profile written by the wizard")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	config := defaultConfig()
+
+	fmt.Println("This wizard writes a starter profile. Press enter to accept the default in brackets.")
+
+	dirs, err := p.ask("Directories to include, comma-separated", ".")
+	if err != nil {
+		return err
+	}
+	config.Dirs = parseCommaSeparated(dirs)
+	for _, dir := range config.Dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Printf("Note: %s is not a directory yet\n", dir)
+		}
+	}
+
+	preset, err := p.choose("Project type", "generic", projectPresetNames())
+	if err != nil {
+		return err
+	}
+	config.IncludeExts = projectPresets[preset].IncludeExts
+	config.IgnoreDirs = projectPresets[preset].IgnoreDirs
+	config.IgnoreFiles = projectPresets[preset].IgnoreFiles
+
+	deps, err := p.ask("Go dependencies to include, comma-separated (empty for none)", "")
+	if err != nil {
+		return err
+	}
+	config.IncludeDeps = parseCommaSeparated(deps)
+
+	if config.Format, err = p.choose("Output format", "text", outputFormats); err != nil {
+		return err
+	}
+
+	var models []string
+	for name := range modelRegistry {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	fmt.Printf("Known models: %s\n", strings.Join(models, ", "))
+	if config.Model, err = p.ask("Target model, or a local Ollama model (empty for none)", ""); err != nil {
+		return err
+	}
+
+	if config.Save, err = p.confirm("Save the output to a file?", true); err != nil {
+		return err
+	}
+	if config.Save {
+		if config.OutputFile, err = p.ask("Output file", config.OutputFile); err != nil {
+			return err
+		}
+	}
+
+	path, err := p.ask("Write the profile to", *outFlag)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		overwrite, err := p.confirm(path+" exists. Overwrite?", false)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			return errors.New("setup cancelled, nothing written")
+		}
+	}
+
+	for _, problem := range checkConfig(config) {
+		fmt.Printf("%s: %s: %s\n", problem.Severity, problem.Field, problem.Message)
+	}
+	if err := SaveConfigFile(path, config); err != nil {
+		return err
+	}
+	fmt.Printf("Profile written to %s. Generate with:\n  codexgigantus --config %s\n", path, path)
+	return nil
+}
@@ -0,0 +1,135 @@
+// sink_cloud.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// oneDriveSimpleUploadLimit is the largest file Microsoft Graph accepts in a
+// single PUT; anything bigger needs an upload session.
+const oneDriveSimpleUploadLimit = 4 << 20
+
+const oneDriveChunkSize = 5 * 320 << 10
+
+// uploadGoogleDrive uploads into the Drive folder with the given ID (the
+// user's root when empty). GOOGLE_DRIVE_TOKEN holds an OAuth access token
+// with the drive.file scope.
+func uploadGoogleDrive(folderID, name string, data []byte) error {
+	token := os.Getenv("GOOGLE_DRIVE_TOKEN")
+	if token == "" {
+		return errors.New("GOOGLE_DRIVE_TOKEN is not set")
+	}
+
+	metadata := map[string]interface{}{"name": name}
+	if folderID != "" {
+		metadata["parents"] = []string{folderID}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	if err != nil {
+		return err
+	}
+	part.Write(metadataJSON)
+	part, err = writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return err
+	}
+	part.Write(data)
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", "https://www.googleapis.com/upload/drive/v3/files?uploadType=multipart", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "multipart/related; boundary="+writer.Boundary())
+	return doSinkRequest(req, nil)
+}
+
+// uploadOneDrive uploads into the given folder path of the signed-in user's
+// OneDrive. ONEDRIVE_TOKEN holds a Microsoft Graph access token with the
+// Files.ReadWrite scope.
+func uploadOneDrive(folder, name string, data []byte) error {
+	token := os.Getenv("ONEDRIVE_TOKEN")
+	if token == "" {
+		return errors.New("ONEDRIVE_TOKEN is not set")
+	}
+
+	itemPath := strings.Trim(strings.Trim(folder, "/")+"/"+name, "/")
+	item := "https://graph.microsoft.com/v1.0/me/drive/root:/" + (&url.URL{Path: itemPath}).EscapedPath() + ":"
+
+	if len(data) <= oneDriveSimpleUploadLimit {
+		req, err := http.NewRequest("PUT", item+"/content", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "text/plain")
+		return doSinkRequest(req, nil)
+	}
+
+	req, err := http.NewRequest("POST", item+"/createUploadSession", strings.NewReader(`{"item":{"@microsoft.graph.conflictBehavior":"replace"}}`))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	var session struct {
+		UploadURL string `json:"uploadUrl"`
+	}
+	if err := doSinkRequest(req, &session); err != nil {
+		return err
+	}
+
+	// The upload URL is pre-authenticated and must not receive the token.
+	for start := 0; start < len(data); start += oneDriveChunkSize {
+		end := start + oneDriveChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		req, err := http.NewRequest("PUT", session.UploadURL, bytes.NewReader(data[start:end]))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+		if err := doSinkRequest(req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func doSinkRequest(req *http.Request, out interface{}) error {
+	resp, err := doHTTP(sinkClient, req, "sink")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: unexpected status %s: %s", req.Method, req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// sink_email.go
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMaxAttachment = 10 << 20
+
+// sendEmail mails the artifact to the ';'-separated recipients. The SMTP
+// server is configured through SMTP_HOST, SMTP_PORT (default 587),
+// SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM. Artifacts larger than
+// SMTP_MAX_ATTACHMENT bytes are not attached; the mail only names the file.
+func sendEmail(recipients, name string, data []byte) error {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return errors.New("SMTP_HOST is not set")
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+	if from == "" {
+		return errors.New("SMTP_FROM is not set")
+	}
+
+	var to []string
+	for _, recipient := range strings.Split(recipients, ";") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			to = append(to, recipient)
+		}
+	}
+	if len(to) == 0 {
+		return errors.New("no recipients given; use email:alice@example.com;bob@example.com")
+	}
+
+	maxAttachment := defaultMaxAttachment
+	if v, err := strconv.Atoi(os.Getenv("SMTP_MAX_ATTACHMENT")); err == nil {
+		maxAttachment = v
+	}
+
+	message := buildEmail(from, to, name, data, len(data) <= maxAttachment)
+
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return sendMail(net.JoinHostPort(host, port), host, port == "465", auth, from, to, message)
+}
+
+// dialSMTP connects over implicit TLS (port 465) or plain SMTP upgraded to
+// STARTTLS whenever the server offers it, using the shared TLS settings.
+func dialSMTP(addr, host string, implicitTLS bool) (*smtp.Client, error) {
+	if implicitTLS {
+		conn, err := tls.Dial("tcp", addr, tlsConfigFor(host))
+		if err != nil {
+			return nil, err
+		}
+		client, err := smtp.NewClient(conn, host)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return client, nil
+	}
+
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(tlsConfigFor(host)); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// sendMail delivers message through the server at addr.
+func sendMail(addr, host string, implicitTLS bool, auth smtp.Auth, from string, to []string, message []byte) error {
+	client, err := dialSMTP(addr, host, implicitTLS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func buildEmail(from string, to []string, name string, data []byte, attach bool) []byte {
+	var buffer bytes.Buffer
+	boundary := fmt.Sprintf("codexgigantus-%d", time.Now().UnixNano())
+
+	buffer.WriteString("From: " + from + "\r\n")
+	buffer.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	buffer.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", "codexgigantus: "+name) + "\r\n")
+	buffer.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n\r\n")
+
+	buffer.WriteString("--" + boundary + "\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	if attach {
+		buffer.WriteString(fmt.Sprintf("The generated artifact %s (%s) is attached.\r\n", name, formatSize(int64(len(data)))))
+	} else {
+		buffer.WriteString(fmt.Sprintf("The generated artifact %s (%s) is too large to attach.\r\n", name, formatSize(int64(len(data)))))
+		buffer.WriteString("It was written to the output location of the run on the generating host.\r\n")
+	}
+
+	if attach {
+		buffer.WriteString("--" + boundary + "\r\n")
+		buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buffer.WriteString("Content-Transfer-Encoding: base64\r\n")
+		buffer.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=%q\r\n\r\n", name))
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			buffer.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buffer.WriteString(encoded + "\r\n")
+	}
+
+	buffer.WriteString("--" + boundary + "--\r\n")
+	return buffer.Bytes()
+}
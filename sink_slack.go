@@ -0,0 +1,87 @@
+// sink_slack.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const slackAPI = "https://slack.com/api"
+
+type slackResponse struct {
+	OK        bool   `json:"ok"`
+	Error     string `json:"error"`
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// uploadSlack posts the artifact as a file to the channel ID using the
+// external upload flow. SLACK_BOT_TOKEN needs the files:write scope and the
+// bot has to be a member of the channel.
+func uploadSlack(channel, name string, data []byte) error {
+	token := os.Getenv("SLACK_BOT_TOKEN")
+	if token == "" {
+		return errors.New("SLACK_BOT_TOKEN is not set")
+	}
+	if channel == "" {
+		return errors.New("no channel given; use slack:<channel-id>")
+	}
+
+	form := url.Values{"filename": {name}, "length": {strconv.Itoa(len(data))}}
+	req, err := http.NewRequest("POST", slackAPI+"/files.getUploadURLExternal", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	upload, err := doSlackRequest(req)
+	if err != nil {
+		return err
+	}
+
+	req, err = http.NewRequest("POST", upload.UploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if err := doSinkRequest(req, nil); err != nil {
+		return err
+	}
+
+	complete, err := json.Marshal(map[string]interface{}{
+		"files":           []map[string]string{{"id": upload.FileID, "title": name}},
+		"channel_id":      channel,
+		"initial_comment": fmt.Sprintf("codexgigantus snapshot: %s (%s)", name, formatSize(int64(len(data)))),
+	})
+	if err != nil {
+		return err
+	}
+	req, err = http.NewRequest("POST", slackAPI+"/files.completeUploadExternal", bytes.NewReader(complete))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	_, err = doSlackRequest(req)
+	return err
+}
+
+// doSlackRequest decodes a Slack Web API response, which reports failures
+// with "ok": false rather than an HTTP status.
+func doSlackRequest(req *http.Request) (*slackResponse, error) {
+	resp := &slackResponse{}
+	if err := doSinkRequest(req, resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("slack %s: %s", req.URL.Path, resp.Error)
+	}
+	return resp, nil
+}
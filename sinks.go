@@ -0,0 +1,61 @@
+// sinks.go
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A sink delivers a finished artifact somewhere other than the local disk.
+// target is the part of the -sink value after "scheme:".
+type sinkFunc func(target, name string, data []byte) error
+
+var sinks = map[string]sinkFunc{
+	"gdrive":   uploadGoogleDrive,
+	"onedrive": uploadOneDrive,
+	"email":    sendEmail,
+	"slack":    uploadSlack,
+}
+
+var sinkClient = &http.Client{Timeout: 5 * time.Minute, Transport: networkTransport}
+
+func sinkSchemes() []string {
+	var schemes []string
+	for scheme := range sinks {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+func validateSinks(specs []string) error {
+	for _, spec := range specs {
+		scheme, _, _ := strings.Cut(spec, ":")
+		if _, ok := sinks[scheme]; !ok {
+			return fmt.Errorf("unknown sink %q (supported: %s)", spec, strings.Join(sinkSchemes(), ", "))
+		}
+	}
+	return nil
+}
+
+// DeliverSinks sends output to every configured sink, named after the
+// output file.
+func DeliverSinks(output string, config *Config) error {
+	name := filepath.Base(config.OutputFile)
+	for _, spec := range config.Sinks {
+		scheme, target, _ := strings.Cut(spec, ":")
+		sink, ok := sinks[scheme]
+		if !ok {
+			return fmt.Errorf("unknown sink %q", spec)
+		}
+		if err := sink(target, name, []byte(output)); err != nil {
+			return fmt.Errorf("delivering to %s: %w", scheme, err)
+		}
+		fmt.Printf("Output delivered to %s\n", spec)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// stdlib.go
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// processStdlib pulls standard library declarations into the results.
+// Specs are a package path ("net/http"), a symbol ("net/http.Server") or a
+// method ("net/http.Server.Serve").
+func processStdlib(config *Config) ([]FileResult, error) {
+	goroot := goRoot()
+	var results []FileResult
+
+	for _, spec := range config.IncludeStd {
+		pkg, symbol := splitStdSpec(spec)
+		dir := filepath.Join(goroot, "src", filepath.FromSlash(pkg))
+		config.Debugf("Including standard library %s from %s", spec, dir)
+
+		found, err := extractStdlib(dir, pkg, symbol, config)
+		if err != nil {
+			return nil, fmt.Errorf("including %s: %w", spec, err)
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("including %s: symbol not found in %s", spec, pkg)
+		}
+		results = append(results, found...)
+	}
+
+	return results, nil
+}
+
+func goRoot() string {
+	if out, err := exec.Command("go", "env", "GOROOT").Output(); err == nil {
+		if root := strings.TrimSpace(string(out)); root != "" {
+			return root
+		}
+	}
+	return runtime.GOROOT()
+}
+
+func splitStdSpec(spec string) (string, string) {
+	slash := strings.LastIndex(spec, "/")
+	dot := strings.Index(spec[slash+1:], ".")
+	if dot < 0 {
+		return spec, ""
+	}
+	return spec[:slash+1+dot], spec[slash+1+dot+1:]
+}
+
+func extractStdlib(dir, pkg, symbol string, config *Config) ([]FileResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isGoFile(entry.Name()) && !strings.HasSuffix(entry.Name(), "_test.go") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var results []FileResult
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		displayPath := "GOROOT/src/" + pkg + "/" + name
+
+		if symbol == "" {
+			results = append(results, NewFileResult(displayPath, "std", src, info.ModTime(), config))
+			continue
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if declMatches(decl, symbol) {
+				start, end := declRange(decl)
+				snippet := src[fset.Position(start).Offset:fset.Position(end).Offset]
+				results = append(results, NewFileResult(displayPath+"#"+symbol, "std", snippet, info.ModTime(), config))
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func declMatches(decl ast.Decl, symbol string) bool {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return d.Name.Name == symbol
+		}
+		return receiverName(d.Recv.List[0].Type)+"."+d.Name.Name == symbol
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == symbol {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					if name.Name == symbol {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func declRange(decl ast.Decl) (token.Pos, token.Pos) {
+	start := decl.Pos()
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	return start, decl.End()
+}
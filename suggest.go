@@ -0,0 +1,236 @@
+// suggest.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// minSuggestionTokens is the smallest saving worth suggesting a rule for.
+const minSuggestionTokens = 100
+
+// ignoreSuggestion is an ignore rule that would drop low-relevance files.
+type ignoreSuggestion struct {
+	Kind   string // "dir", "ext" or "file"
+	Value  string
+	Files  int
+	Tokens int
+	Class  string
+}
+
+func (s ignoreSuggestion) flag() string {
+	return fmt.Sprintf("-ignore-%s %s", s.Kind, s.Value)
+}
+
+// lowRelevance reports whether a file probably adds tokens without helping
+// a model: vendored and generated code and data files.
+func lowRelevance(result FileResult) bool {
+	return result.Class == "vendored" || result.Class == "generated" || result.Class == "data"
+}
+
+// suggestIgnores proposes ignore rules for the low-relevance files of a
+// run, largest saving first. A rule is only suggested when it would not
+// also drop a relevant file: a directory below one of dirs whose included
+// files are all low relevance, else an extension (or the name of a dotfile
+// without one), else the file itself. Directory and file rules are anchored
+// at the walked directory (/vendor), so they drop exactly the files counted
+// for them.
+func suggestIgnores(results []FileResult, dirs []string) []ignoreSuggestion {
+	var relevant, candidates []FileResult
+	for _, result := range results {
+		if result.Source != "fs" {
+			continue
+		}
+		if lowRelevance(result) {
+			candidates = append(candidates, result)
+		} else {
+			relevant = append(relevant, result)
+		}
+	}
+
+	matchesRelevant := func(kind, value string) bool {
+		for _, result := range relevant {
+			if ruleMatches(kind, value, result.Path, dirs) {
+				return true
+			}
+		}
+		return false
+	}
+
+	suggestions := make(map[string]*ignoreSuggestion)
+	for _, result := range candidates {
+		kind, value := "", ""
+		parents := parentDirs(result.Path, dirs)
+		for i := range parents {
+			if dir := "/" + strings.Join(parents[:i+1], "/"); !matchesRelevant("dir", dir) {
+				kind, value = "dir", dir
+				break
+			}
+		}
+		// A dotfile such as .DS_Store has no extension; it is matched by
+		// name wherever it turns up.
+		name := filepath.Base(result.Path)
+		ext := strings.TrimPrefix(filepath.Ext(strings.TrimPrefix(name, ".")), ".")
+		if kind == "" && ext != "" && !matchesRelevant("ext", ext) {
+			kind, value = "ext", ext
+		}
+		if kind == "" && ext == "" && strings.HasPrefix(name, ".") && !matchesRelevant("file", name) {
+			kind, value = "file", name
+		}
+		if kind == "" {
+			kind, value = "file", "/"+walkedPath(result.Path, dirs)
+		}
+
+		key := kind + ":" + value
+		suggestion, ok := suggestions[key]
+		if !ok {
+			suggestion = &ignoreSuggestion{Kind: kind, Value: value, Class: result.Class}
+			suggestions[key] = suggestion
+		}
+		suggestion.Files++
+		suggestion.Tokens += result.TokenCount
+		if suggestion.Class != result.Class {
+			suggestion.Class = "mixed"
+		}
+	}
+
+	var list []ignoreSuggestion
+	for _, suggestion := range suggestions {
+		if suggestion.Tokens >= minSuggestionTokens {
+			list = append(list, *suggestion)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Tokens != list[j].Tokens {
+			return list[i].Tokens > list[j].Tokens
+		}
+		return list[i].flag() < list[j].flag()
+	})
+	return list
+}
+
+// parentDirs lists the directory names of path below the walked directory
+// it belongs to, outermost first.
+func parentDirs(path string, roots []string) []string {
+	rel := path
+	for _, root := range canonicalDirs(roots) {
+		if r, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+			break
+		}
+	}
+	var dirs []string
+	for _, elem := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+		if elem != "" && elem != "." && elem != ".." {
+			dirs = append(dirs, elem)
+		}
+	}
+	return dirs
+}
+
+// walkedPath returns path relative to the walked directory it belongs to,
+// slash-separated.
+func walkedPath(path string, roots []string) string {
+	return filepath.ToSlash(filepath.Join(append(parentDirs(path, roots), filepath.Base(path))...))
+}
+
+// ruleMatches applies one ignore rule to path, a result of a walk of one
+// of roots, the way the walk does.
+func ruleMatches(kind, value, path string, roots []string) bool {
+	if kind != "ext" && strings.HasPrefix(value, "/") {
+		return anchoredMatch(walkedPath(path, roots), []string{value})
+	}
+	probe := &Config{}
+	switch kind {
+	case "dir":
+		probe.IgnoreDirs = []string{value}
+		return shouldIgnoreDir(filepath.Dir(path), probe)
+	case "ext":
+		probe.IgnoreExts = []string{value}
+	case "file":
+		probe.IgnoreFiles = []string{value}
+	}
+	return shouldIgnoreFile(path, probe)
+}
+
+// reportSuggestions prints the suggestions and, with --apply-suggestions,
+// adds them to the --config file.
+func reportSuggestions(results []FileResult, config *Config, w io.Writer) {
+	suggestions := suggestIgnores(results, config.Dirs)
+	if len(suggestions) == 0 {
+		return
+	}
+
+	total := totalTokens(results)
+	fmt.Fprintln(w, "Suggested ignore rules:")
+	for _, s := range suggestions {
+		share := 0.0
+		if total > 0 {
+			share = float64(s.Tokens) * 100 / float64(total)
+		}
+		fmt.Fprintf(w, "  %-40s saves %d tokens (%.1f%%) in %d %s file(s)\n", s.flag(), s.Tokens, share, s.Files, s.Class)
+	}
+
+	if !config.ApplySuggestions {
+		return
+	}
+	if err := applySuggestions(config.ConfigFile, suggestions); err != nil {
+		config.Warn("suggestions", config.ConfigFile, "not applied: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "Added %d rule(s) to %s\n", len(suggestions), config.ConfigFile)
+}
+
+// applySuggestions appends the rules to the ignore lists of a codexgigantus
+// JSON config, leaving its other fields as they are.
+func applySuggestions(path string, suggestions []ignoreSuggestion) error {
+	if path == "" {
+		return errors.New("no --config file to add them to")
+	}
+	if name := filepath.Base(path); name == "repomix.config.json" || name == ".gitingest" || strings.HasSuffix(name, ".toml") {
+		return errors.New("only codexgigantus JSON configs can be updated")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	keys := map[string]string{"dir": "ignore_dirs", "ext": "ignore_exts", "file": "ignore_files"}
+	for _, s := range suggestions {
+		key := keys[s.Kind]
+		var values []string
+		if raw, ok := fields[key]; ok {
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		exists := false
+		for _, value := range values {
+			exists = exists || value == s.Value
+		}
+		if exists {
+			continue
+		}
+		raw, err := json.Marshal(append(values, s.Value))
+		if err != nil {
+			return err
+		}
+		fields[key] = raw
+	}
+
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	return SaveOutput(string(data)+"\n", path)
+}
@@ -0,0 +1,52 @@
+// suggest_test.go
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSuggestIgnoresAnchorsRules(t *testing.T) {
+	var results []FileResult
+	for path, class := range map[string]string{
+		"vendor/lib/a.go": "vendored",
+		"vendor/lib/b.go": "vendored",
+		"src/vendor/c.go": "source",
+		"data/big.json":   "data",
+		"data/small.txt":  "docs",
+		"conf/big.json":   "config",
+	} {
+		results = append(results, FileResult{Path: path, Source: "fs", Class: class, TokenCount: 200})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	var got []string
+	for _, s := range suggestIgnores(results, []string{"."}) {
+		got = append(got, s.flag())
+	}
+	sort.Strings(got)
+	want := "-ignore-dir /vendor,-ignore-file /data/big.json"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestSuggestIgnoresDotfilesByName(t *testing.T) {
+	results := []FileResult{
+		{Path: ".DS_Store", Source: "fs", Class: "data", TokenCount: 80},
+		{Path: "src/.DS_Store", Source: "fs", Class: "data", TokenCount: 80},
+		{Path: "src/main.go", Source: "fs", Class: "source", TokenCount: 200},
+		{Path: "src/.eslintrc.json", Source: "fs", Class: "data", TokenCount: 200},
+	}
+
+	var got []string
+	for _, s := range suggestIgnores(results, []string{"."}) {
+		got = append(got, s.flag())
+	}
+	sort.Strings(got)
+	want := "-ignore-ext json,-ignore-file .DS_Store"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ","), want)
+	}
+}
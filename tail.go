@@ -0,0 +1,71 @@
+// tail.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tailWriter streams the text output of a --tail run into the output file
+// while files are still being read, so tail -f shows it as it grows.
+type tailWriter struct {
+	path    string
+	file    *os.File
+	written strings.Builder
+	files   int
+}
+
+// openTail creates the output file for a --tail run. The name is expanded
+// before the file count and token count are known.
+func openTail(config *Config) (*tailWriter, error) {
+	path := expandTemplate(config.OutputFile, templateVars(config, 0, 0))
+	file, err := os.Create(longPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return &tailWriter{path: path, file: file}, nil
+}
+
+// add appends one file to the output and prints the progress to stderr. A
+// nil tailWriter ignores the call.
+func (t *tailWriter) add(result FileResult, config *Config) {
+	if t == nil {
+		return
+	}
+	chunk := formatText([]FileResult{result}, config)
+	if _, err := t.file.WriteString(chunk); err != nil {
+		config.Warn("tail", t.path, "writing output: %v", err)
+		return
+	}
+	t.written.WriteString(chunk)
+	t.files++
+	fmt.Fprintf(os.Stderr, "[%d files, %s] %s\n", t.files, formatSize(int64(t.written.Len())), result.Path)
+}
+
+// finish writes the final output. When the streamed text is a prefix of it
+// the rest is appended; otherwise (sections, a banner, reordered or
+// transformed files) the file is rewritten in place. Output going to
+// another name replaces the streamed file.
+func (t *tailWriter) finish(output, filename string) error {
+	defer t.file.Close()
+	if filename != t.path {
+		t.file.Close()
+		os.Remove(longPath(t.path))
+		return SaveOutput(output, filename)
+	}
+
+	if rest, ok := strings.CutPrefix(output, t.written.String()); ok {
+		if _, err := t.file.WriteString(rest); err != nil {
+			return err
+		}
+		return t.file.Close()
+	}
+	if err := t.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := t.file.WriteAt([]byte(output), 0); err != nil {
+		return err
+	}
+	return t.file.Close()
+}
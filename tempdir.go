@@ -0,0 +1,166 @@
+// tempdir.go
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultTempMaxAge is how old a leftover directory in the temp root may get
+// before the next run removes it.
+const defaultTempMaxAge = 24 * time.Hour
+
+// tempLockSuffix names the lock file a run holds next to each of its temp
+// directories (kept outside, as clones need an empty directory), so other
+// runs do not remove them.
+const tempLockSuffix = ".lock"
+
+// tempPrefixes are the name prefixes of the directories makeTempDir
+// creates; nothing else in the temp root is ever removed.
+var tempPrefixes = []string{"clone-", "sandbox-"}
+
+var (
+	tempMu sync.Mutex
+	// tempDirs maps the directories of this run to their held lock.
+	tempDirs = make(map[string]*os.File)
+)
+
+// tempRoot is where clones and sandboxes live: $CODEXGIGANTUS_TMPDIR, or a
+// codexgigantus directory in the system temp dir.
+func tempRoot() string {
+	if root := os.Getenv("CODEXGIGANTUS_TMPDIR"); root != "" {
+		return root
+	}
+	return filepath.Join(os.TempDir(), "codexgigantus")
+}
+
+// makeTempDir creates a directory below the temp root after removing stale
+// leftovers and enforcing $CODEXGIGANTUS_TMP_QUOTA. Release it with
+// removeTempDir; directories still registered are removed on interrupt.
+func makeTempDir(pattern string) (string, error) {
+	root := tempRoot()
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", err
+	}
+
+	maxAge := defaultTempMaxAge
+	if value := os.Getenv("CODEXGIGANTUS_TMP_MAX_AGE"); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("CODEXGIGANTUS_TMP_MAX_AGE: %w", err)
+		}
+		maxAge = age
+	}
+	removeStaleTempDirs(root, maxAge)
+
+	if value := os.Getenv("CODEXGIGANTUS_TMP_QUOTA"); value != "" {
+		quota, err := parseSize(value)
+		if err != nil {
+			return "", fmt.Errorf("CODEXGIGANTUS_TMP_QUOTA: %w", err)
+		}
+		if used := dirSize(root); used >= quota {
+			return "", fmt.Errorf("temp root %s holds %s, reaching its %s quota", root, formatSize(used), formatSize(quota))
+		}
+	}
+
+	dir, err := os.MkdirTemp(root, pattern)
+	if err != nil {
+		return "", err
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	tempMu.Lock()
+	tempDirs[dir] = lock
+	tempMu.Unlock()
+	return dir, nil
+}
+
+func removeTempDir(dir string) {
+	tempMu.Lock()
+	if lock := tempDirs[dir]; lock != nil {
+		lock.Close()
+	}
+	delete(tempDirs, dir)
+	tempMu.Unlock()
+	os.RemoveAll(dir)
+	os.Remove(dir + tempLockSuffix)
+}
+
+// removeStaleTempDirs deletes what crashed or killed runs left behind: the
+// directories makeTempDir created that are older than maxAge and whose
+// lock no live run holds. The temp root may be shared, so other entries
+// are left alone.
+func removeStaleTempDirs(root string, maxAge time.Duration) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !isTempDirName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) <= maxAge {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if !dirLocked(dir) {
+			os.RemoveAll(dir)
+			os.Remove(dir + tempLockSuffix)
+		}
+	}
+}
+
+func isTempDirName(name string) bool {
+	for _, prefix := range tempPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func dirSize(root string) int64 {
+	var size int64
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// removeTempDirsOnInterrupt cleans up the registered temp directories when
+// the process is interrupted, since deferred removals do not run then. It
+// exits with 128 plus the signal number, as a shell would: 130 for SIGINT
+// and 143 for SIGTERM.
+func removeTempDirsOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := <-signals
+		tempMu.Lock()
+		for dir, lock := range tempDirs {
+			lock.Close()
+			os.RemoveAll(dir)
+			os.Remove(dir + tempLockSuffix)
+		}
+		if received == syscall.SIGTERM {
+			os.Exit(143)
+		}
+		os.Exit(130)
+	}()
+}
@@ -0,0 +1,78 @@
+// tempdir_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRemoveStaleTempDirs(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("CODEXGIGANTUS_TMPDIR", root)
+	old := time.Now().Add(-48 * time.Hour)
+	age := func(path string) {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mkdir := func(name string) string {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Join(dir, "sub"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "sub", "file"), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		age(dir)
+		return dir
+	}
+
+	// Created first, as makeTempDir cleans up itself.
+	live, err := makeTempDir("sandbox-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeTempDir(live)
+	age(live)
+
+	stale := mkdir("clone-stale")
+	if err := os.WriteFile(stale+tempLockSuffix, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	recent := filepath.Join(root, "sandbox-recent")
+	if err := os.Mkdir(recent, 0700); err != nil {
+		t.Fatal(err)
+	}
+	foreign := mkdir("other-tool")
+	notDir := filepath.Join(root, "clone-file")
+	if err := os.WriteFile(notDir, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	age(notDir)
+
+	removeStaleTempDirs(root, 24*time.Hour)
+
+	exists := func(path string) bool {
+		_, err := os.Lstat(path)
+		return err == nil
+	}
+	if exists(stale) || exists(stale+tempLockSuffix) {
+		t.Error("stale directory or its lock file was kept")
+	}
+	if !exists(recent) {
+		t.Error("recent directory was removed")
+	}
+	if !exists(filepath.Join(foreign, "sub", "file")) || !exists(notDir) {
+		t.Error("an entry the temp root does not own was touched")
+	}
+	// Without file locks (Windows and others) the age alone counts.
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd":
+		if !exists(live) {
+			t.Error("directory of a live run was removed")
+		}
+	}
+}
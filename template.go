@@ -0,0 +1,61 @@
+// template.go
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var templateVarRe = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// templateVars returns the placeholders shared by banners, prompt templates
+// and output file names. Values are computed on use, so a template without
+// {git_branch} never runs git.
+func templateVars(config *Config, fileCount, tokenCount int) map[string]func() string {
+	now := time.Now()
+	files := func() string { return strconv.Itoa(fileCount) }
+	tokens := func() string { return strconv.Itoa(tokenCount) }
+
+	return map[string]func() string{
+		"date":        func() string { return now.Format("2006-01-02") },
+		"time":        func() string { return now.Format(time.RFC3339) },
+		"host":        hostname,
+		"run_id":      func() string { return config.RunID },
+		"profile":     func() string { return config.Profile },
+		"git_branch":  func() string { return gitBranch(config.Dirs) },
+		"file_count":  files,
+		"files":       files,
+		"token_count": tokens,
+		"tokens":      tokens,
+	}
+}
+
+// expandTemplate fills in the known placeholders and leaves any other
+// braces, such as a file header's {path}, untouched.
+func expandTemplate(text string, vars map[string]func() string) string {
+	return templateVarRe.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := vars[placeholder[1:len(placeholder)-1]]; ok {
+			return value()
+		}
+		return placeholder
+	})
+}
+
+func hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}
+
+// totalTokens sums the per-file token estimates.
+func totalTokens(results []FileResult) int {
+	total := 0
+	for _, result := range results {
+		total += result.TokenCount
+	}
+	return total
+}
@@ -0,0 +1,13 @@
+// Package sample is the corpus the golden tests render.
+package sample
+
+import "fmt"
+
+// Greet returns a greeting for name.
+func Greet(name string) string {
+	return fmt.Sprintf("Hello, %s!", name)
+}
+
+func add(a, b int) int {
+	return a + b
+}
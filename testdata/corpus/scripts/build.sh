@@ -0,0 +1,3 @@
+#!/bin/sh
+# A deliberately long line so -wrap and -max-file-size have something to cut:
+go build -ldflags "-s -w -X main.version=1.2.3 -X main.commit=abcdef0 -X main.date=2024-01-01" -o sample .
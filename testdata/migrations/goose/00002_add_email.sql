@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email TEXT;
+CREATE UNIQUE INDEX users_email ON users (email);
+INSERT INTO users (name) VALUES ('admin');
+
+-- +goose Down
+DROP INDEX users_email;
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users RENAME COLUMN name TO full_name;
+
+-- +goose Down
+ALTER TABLE users RENAME COLUMN full_name TO name;
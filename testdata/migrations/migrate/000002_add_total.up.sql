@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN total NUMERIC(10, 2) DEFAULT 0;
+ALTER TABLE orders ALTER COLUMN user_id TYPE BIGINT;
@@ -0,0 +1,5 @@
+ActiveRecord::Schema[7.1].define(version: 2024_01_01_000000) do
+  create_table "posts", force: :cascade do |t|
+    t.string "title"
+  end
+end
@@ -0,0 +1,92 @@
+// transforms.go
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// applyTransforms runs the enabled content transforms on a single file.
+func applyTransforms(content, path string, config *Config) string {
+	if config.NormalizeData {
+		content = normalizeData(content, path, config.MaxArrayItems)
+	}
+	if config.WrapColumn > 0 {
+		content = wrapLongLines(content, config.WrapColumn, config.WrapMarker)
+	}
+	return content
+}
+
+// wrapLongLines hard-wraps lines longer than column runes. Every piece but
+// the last ends with marker so the original line can be reassembled.
+func wrapLongLines(content string, column int, marker string) string {
+	lines := strings.Split(content, "\n")
+	wrapped := false
+
+	for i, line := range lines {
+		if utf8.RuneCountInString(line) <= column {
+			continue
+		}
+		wrapped = true
+
+		var pieces []string
+		runes := []rune(line)
+		for len(runes) > column {
+			pieces = append(pieces, string(runes[:column])+marker)
+			runes = runes[column:]
+		}
+		lines[i] = strings.Join(append(pieces, string(runes)), "\n")
+	}
+
+	if !wrapped {
+		return content
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unwrapLongLines undoes wrapLongLines: a piece of exactly column runes
+// followed by marker is joined with the next line.
+func unwrapLongLines(content string, column int, marker string) string {
+	pieceLength := column + utf8.RuneCountInString(marker)
+	var lines []string
+	var pending strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasSuffix(line, marker) && utf8.RuneCountInString(line) == pieceLength {
+			pending.WriteString(strings.TrimSuffix(line, marker))
+			continue
+		}
+		lines = append(lines, pending.String()+line)
+		pending.Reset()
+	}
+	if pending.Len() > 0 {
+		lines = append(lines, pending.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// detectWrapColumn guesses the column files were hard-wrapped at: no line
+// is longer than a piece, and at least two lines are pieces of exactly
+// that length ending in marker. It returns 0 when they do not look
+// wrapped.
+func detectWrapColumn(files []FileResult, marker string) int {
+	markerLength := utf8.RuneCountInString(marker)
+	longest, pieces := 0, 0
+	for _, file := range files {
+		for _, line := range strings.Split(file.Content, "\n") {
+			length := utf8.RuneCountInString(line)
+			switch {
+			case length > longest:
+				longest, pieces = length, 0
+				if strings.HasSuffix(line, marker) {
+					pieces = 1
+				}
+			case length == longest && strings.HasSuffix(line, marker):
+				pieces++
+			}
+		}
+	}
+	if pieces < 2 || longest <= markerLength {
+		return 0
+	}
+	return longest - markerLength
+}
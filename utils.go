@@ -7,26 +7,148 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
-func GenerateOutput(results []FileResult, config *Config) string {
+func GenerateOutput(results []FileResult, sections []Section, config *Config) string {
+	results = tagSources(results)
+
+	// Data formats have to stay parseable, so banners, prompts and
+	// sections are left out (JSON carries sections in their own field).
+	switch config.Format {
+	case "json":
+		return formatJSON(results, sections, config)
+	case "json-result":
+		return formatRunResult(results, sections, config)
+	case "csv":
+		return formatDelimited(results, ',')
+	case "tsv":
+		return formatDelimited(results, '\t')
+	}
+
+	body := ""
+	if config.Format != "report" {
+		body = renderSections(sections, config.Format)
+	}
+	switch config.Format {
+	case "repomix":
+		body += formatRepomix(results)
+	case "aider":
+		body += formatAider(results)
+	case "codemap":
+		body += formatCodemap(results)
+	case "report":
+		body += formatReport(results, sections, config)
+	default:
+		body += formatText(results, config)
+	}
+
+	if config.PromptTemplate != "" {
+		vars := templateVars(config, len(results), estimateTokens(body, config.Tokenizer))
+		body = applyPromptTemplate(config.PromptTemplate, config.PromptDetails, body, vars)
+	}
+
+	if config.Banner {
+		return renderBanner(config.BannerText, results, body, config) + body
+	}
+	return body
+}
+
+// tagSources prefixes every path with its source when results come from more
+// than one source, so each blob can be traced back (fs:, dep:, std:, git:).
+func tagSources(results []FileResult) []FileResult {
+	sources := make(map[string]bool)
+	for _, result := range results {
+		sources[result.Source] = true
+	}
+	if len(sources) < 2 {
+		return results
+	}
+
+	tagged := make([]FileResult, len(results))
+	for i, result := range results {
+		result.Path = result.Source + ":" + result.Path
+		tagged[i] = result
+	}
+	return tagged
+}
+
+// Generate runs the whole pipeline for config and returns the output
+// together with the files it contains. With config.Delta only the files
+// changed since the previous --delta run are included, and this run's
+// manifest is recorded before Generate returns.
+func Generate(config *Config) (string, []FileResult, error) {
+	output, results, included, err := generate(config)
+	if err == nil && config.Delta {
+		recordManifest(included, config)
+	}
+	return output, results, err
+}
+
+// generate is Generate without recording the --delta manifest; included
+// is what the manifest should list once the output has been delivered.
+func generate(config *Config) (output string, results, included []FileResult, err error) {
+	if err := ValidateConfig(config); err != nil {
+		return "", nil, nil, err
+	}
+	if config.RunID == "" {
+		config.RunID = newRunID()
+	}
+	if config.Warnings == nil {
+		config.Warnings = &Warnings{}
+	}
+	if err := resolveModel(config); err != nil {
+		return "", nil, nil, err
+	}
+
+	if results, err = ProcessFiles(config); err != nil {
+		return "", nil, nil, err
+	}
+	included = results
+	var deltaSections []Section
+	if config.Delta {
+		if results, deltaSections, err = deltaResults(results, config); err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	sections, err := BuildSections(results, config)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	sections = append(deltaSections, sections...)
+
+	return GenerateOutput(results, sections, config), results, included, nil
+}
+
+func formatText(results []FileResult, config *Config) string {
 	var buffer bytes.Buffer
+	isDelimiter := textDelimiter(config.FileHeader, config.FileFooter)
 
 	for _, result := range results {
 		if config.ShowFuncs && isGoFile(result.Path) {
 			funcs := extractFunctions(result.Content)
 			if len(funcs) > 0 {
-				buffer.WriteString(fmt.Sprintf("File: %s\n", result.Path))
+				buffer.WriteString(expandFileDelimiter(config.FileHeader, result) + "\n")
 				for _, f := range funcs {
 					buffer.WriteString(fmt.Sprintf("Function: %s\n", f))
 				}
 				buffer.WriteString("\n")
 			}
 		} else {
-			buffer.WriteString(fmt.Sprintf("File: %s\n", result.Path))
-			buffer.WriteString(result.Content)
+			buffer.WriteString(expandFileDelimiter(config.FileHeader, result) + "\n")
+			buffer.WriteString(escapeDelimiterLines(result.Content, isDelimiter))
+			if result.Truncated {
+				buffer.WriteString(fmt.Sprintf("\n[truncated: %d of %d bytes shown]", len(result.Content), result.Size))
+			}
+			if config.FileFooter != "" {
+				buffer.WriteString("\n" + expandFileDelimiter(config.FileFooter, result))
+			}
 			buffer.WriteString("\n\n")
 		}
 	}
@@ -34,8 +156,138 @@ func GenerateOutput(results []FileResult, config *Config) string {
 	return buffer.String()
 }
 
+// expandFileDelimiter fills the placeholders of a per-file header or footer.
+func expandFileDelimiter(delimiter string, result FileResult) string {
+	return strings.NewReplacer(
+		"{path}", result.Path,
+		"{language}", result.Language,
+		"{class}", result.Class,
+		"{size}", strconv.FormatInt(result.Size, 10),
+		"{tokens}", strconv.Itoa(result.TokenCount),
+	).Replace(delimiter)
+}
+
+// SaveOutput writes through a temporary file in the same directory and
+// renames it into place, so a failed write never leaves a truncated file.
+// A symlink is followed, so its target is replaced rather than the link,
+// and an existing file keeps its permissions.
 func SaveOutput(output, filename string) error {
-	return os.WriteFile(filename, []byte(output), 0644)
+	filename = followSymlinks(filename)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(longPath(filename)); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dir := filepath.Dir(filename)
+	if err := ensureDiskSpace(dir, int64(len(output))); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(longPath(dir), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(output); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), longPath(filename))
+}
+
+// followSymlinks returns the file a chain of symlinks ends at, even when
+// that file does not exist yet.
+func followSymlinks(filename string) string {
+	for hops := 0; hops < 40; hops++ {
+		target, err := os.Readlink(filename)
+		if err != nil {
+			return filename
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(filename), target)
+		}
+		filename = target
+	}
+	return filename
+}
+
+// saveIfChanged saves output unless filename already holds the same
+// content, in which case the file, and its modification time, are left
+// alone so scheduled runs do not wake up whatever watches it. The run ID
+// and timestamps in the header of the output do not count as a change
+// (see stableOutput).
+func saveIfChanged(output, filename string, config *Config) (bool, error) {
+	if sameContent(output, filename, config) {
+		return false, nil
+	}
+	return true, SaveOutput(output, filename)
+}
+
+// sameContent compares output with filename. Files whose size differs by
+// more than the header fields can account for are not read.
+func sameContent(output, filename string, config *Config) bool {
+	file, err := os.Open(longPath(filename))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if diff := info.Size() - int64(len(output)); diff < -volatileSlack || diff > volatileSlack {
+		return false
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return false
+	}
+	old := string(data)
+	return old == output || stableOutput(old, config) == stableOutput(output, config)
+}
+
+// volatileSlack is how many bytes the header fields stableOutput removes
+// may change the size of an output by.
+const volatileSlack = 256
+
+var (
+	jsonVolatileRe   = regexp.MustCompile(`(?m)^(  "(?:run_id|started|duration_ms)":).*$`)
+	reportRunRe      = regexp.MustCompile(`(?m)^- Run: .*$`)
+	bannerVolatileRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})|\d{8}T\d{6}Z-[0-9a-f]{6}|\d{4}-\d{2}-\d{2}`)
+)
+
+// stableOutput removes what differs between two runs over the same files:
+// the top-level run_id, started and duration_ms fields of the json and
+// json-result formats, the "- Run:" line of the report summary, and the
+// run ID, {time} and {date} in a --banner. File contents are never
+// touched, so a changed file always counts as a change.
+func stableOutput(output string, config *Config) string {
+	switch config.Format {
+	case "json", "json-result":
+		return jsonVolatileRe.ReplaceAllString(output, "$1")
+	case "report":
+		if loc := reportRunRe.FindStringIndex(output); loc != nil {
+			output = output[:loc[0]] + "- Run:" + output[loc[1]:]
+		}
+	}
+	if config.Banner {
+		// The banner is the first lines of the output, one per line of
+		// its template.
+		lines := strings.SplitN(output, "\n", strings.Count(config.BannerText, "\n")+2)
+		for i := 0; i < len(lines)-1; i++ {
+			if config.RunID != "" {
+				lines[i] = strings.ReplaceAll(lines[i], config.RunID, "")
+			}
+			lines[i] = bannerVolatileRe.ReplaceAllString(lines[i], "")
+		}
+		output = strings.Join(lines, "\n")
+	}
+	return output
 }
 
 func isGoFile(path string) bool {
@@ -70,7 +322,3 @@ func extractFunctions(content string) []string {
 
 	return funcs
 }
-
-func Debug(format string, args ...interface{}) {
-	fmt.Printf("DEBUG: "+format+"\n", args...)
-}
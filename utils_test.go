@@ -0,0 +1,89 @@
+// utils_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveIfChangedIgnoresRunHeader(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(config *Config)
+	}{
+		{name: "text"},
+		{name: "json", setup: func(c *Config) { c.Format = "json" }},
+		{name: "json-result", setup: func(c *Config) { c.Format = "json-result" }},
+		{name: "report", setup: func(c *Config) { c.Format = "report" }},
+		{name: "banner", setup: func(c *Config) { c.Banner = true }},
+		{name: "custom banner", setup: func(c *Config) {
+			c.Banner = true
+			c.BannerText = "Run {run_id}\nDate {date}, time {time}"
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "output.txt")
+			run := func(runID string, started time.Time, edit func([]FileResult)) bool {
+				config := defaultConfig()
+				if tc.setup != nil {
+					tc.setup(config)
+				}
+				config.RunID = runID
+				config.started = started
+				results, err := ProcessFS(os.DirFS(filepath.Join("testdata", "corpus")), config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if edit != nil {
+					edit(results)
+				}
+				changed, err := saveIfChanged(GenerateOutput(results, nil, config), output, config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return changed
+			}
+
+			start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			if !run("20260102T030405Z-aaaaaa", start, nil) {
+				t.Fatal("first run reported unchanged")
+			}
+			if run("20260103T101010Z-bbbbbb", start.Add(25*time.Hour), nil) {
+				t.Error("second run over the same files reported changed")
+			}
+			edited := func(results []FileResult) { results[0].Content += "// 2026-01-04T00:00:00Z\n" }
+			if !run("20260104T101010Z-cccccc", start.Add(49*time.Hour), edited) {
+				t.Error("run with a changed file reported unchanged")
+			}
+		})
+	}
+}
+
+func TestSaveOutputKeepsModeAndSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real.txt")
+	if err := os.WriteFile(target, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	if err := SaveOutput("new", link); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("the symlink was replaced: %v", err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "new" {
+		t.Errorf("target holds %q, want new", data)
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("target mode changed: %v %v", info.Mode(), err)
+	}
+}
@@ -0,0 +1,51 @@
+// warnings.go
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Warning is a problem that did not stop the run, such as a skipped or
+// truncated file. Kind is a short machine-readable tag.
+type Warning struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// Warnings collects the warnings of one run. Config copies share it, so
+// warnings raised while processing dependencies or chunks end up together.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+func (w *Warnings) add(warning Warning) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, warning)
+}
+
+// List returns the collected warnings in the order they were raised.
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.list...)
+}
+
+// Warn records a warning and logs it, even without debug output.
+func (config *Config) Warn(kind, path, format string, args ...interface{}) {
+	warning := Warning{Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)}
+	if config.Warnings != nil {
+		config.Warnings.add(warning)
+	}
+	if path != "" {
+		config.logger().Printf("WARNING"+config.runTag()+": %s: %s", path, warning.Message)
+		return
+	}
+	config.logger().Printf("WARNING"+config.runTag()+": %s", warning.Message)
+}